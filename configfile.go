@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"mcrcon-go/mcrcon"
+)
+
+// configFile holds the settings read from a config file: the top-level
+// settings and one section per profile
+type configFile struct {
+	defaults []configSetting
+	profiles map[string][]configSetting
+}
+
+// defaultProfile is the profile used when none is selected
+const defaultProfile = "default"
+
+// configSetting is a "key = value" line, where key is a long option name
+type configSetting struct {
+	key, value string
+	line       int
+}
+
+// applyConfigFile applies the config file given with --config, or the
+// default one if it exists, including the profile selected with --profile.
+// The server directory given with --server-dir, or else by the config
+// file, is read first so that the config file's settings override it.
+func applyConfigFile(args []string, flags []cliFlag, config *mcrcon.Config) error {
+	path, explicit := findOptionValue(args, flags, "config")
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	profile, _ := findOptionValue(args, flags, "profile")
+
+	var settings []configSetting
+	if path == "" {
+		if profile != "" {
+			return fmt.Errorf("no config file to select profile %q from", profile)
+		}
+	} else {
+		cfg, err := loadConfigFile(path, explicit || profile != "")
+		if err != nil {
+			return err
+		}
+		if settings, err = cfg.settings(profile); err != nil {
+			return err
+		}
+	}
+
+	dir, ok := findOptionValue(args, flags, "server-dir")
+	if !ok {
+		dir, ok = lastSetting(settings, "server-dir")
+	}
+	if ok {
+		if err := applyServerDir(dir, config); err != nil {
+			return err
+		}
+	}
+
+	return applySettings(settings, flags)
+}
+
+// defaultConfigPath returns ~/.config/mcrcon/config or the platform
+// equivalent, or an empty string if there is no config directory
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "mcrcon", "config")
+}
+
+// loadConfigFile reads the config file at path. A missing file is only an
+// error if required is set, i.e. the path was given explicitly.
+func loadConfigFile(path string, required bool) (*configFile, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return &configFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	cfg, err := parseConfigFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// parseConfigFile parses lines of "key = value" settings. Settings before
+// the first section header apply to every profile; settings in a
+// [profiles.name] (or just [name]) section apply when that profile is
+// selected, and those in [default] when none is. Blank lines and lines
+// starting with '#' or ';' are ignored, and a key without a value is
+// shorthand for "key = true".
+func parseConfigFile(r io.Reader) (*configFile, error) {
+	cfg := &configFile{profiles: make(map[string][]configSetting)}
+	section := "" // the current profile, or "" before the first section
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			name, ok := strings.CutSuffix(line[1:], "]")
+			name = strings.TrimPrefix(strings.TrimSpace(name), "profiles.")
+			if !ok || name == "" {
+				return nil, fmt.Errorf("line %d: invalid section header %q", n, line)
+			}
+			if _, ok := cfg.profiles[name]; !ok {
+				cfg.profiles[name] = nil
+			}
+			section = name
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		value = unquote(strings.TrimSpace(value))
+		if !hasValue {
+			value = "true"
+		}
+		setting := configSetting{key: key, value: value, line: n}
+		if section == "" {
+			cfg.defaults = append(cfg.defaults, setting)
+		} else {
+			cfg.profiles[section] = append(cfg.profiles[section], setting)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// unquote removes matching double or single quotes around value
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// settings returns the top-level settings followed by those of the
+// selected profile, or of the default profile if none is selected
+func (cfg *configFile) settings(profile string) ([]configSetting, error) {
+	settings := cfg.defaults
+	if profile == "" {
+		profile = defaultProfile
+		if _, ok := cfg.profiles[profile]; !ok {
+			profile = ""
+		}
+	}
+	if profile != "" {
+		section, ok := cfg.profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q (available: %s)", profile, cfg.profileNames())
+		}
+		settings = append(settings[:len(settings):len(settings)], section...)
+	}
+	return settings, nil
+}
+
+// lastSetting returns the value of the last setting with the given key
+func lastSetting(settings []configSetting, key string) (string, bool) {
+	for _, setting := range slices.Backward(settings) {
+		if setting.key == key {
+			return setting.value, true
+		}
+	}
+	return "", false
+}
+
+// applySettings applies config file settings as if they were given as
+// long options. server-dir has already been read by applyConfigFile.
+func applySettings(settings []configSetting, flags []cliFlag) error {
+	for _, setting := range settings {
+		flag := findLongFlag(flags, setting.key)
+		if flag == nil || setting.key == "config" || setting.key == "profile" {
+			return fmt.Errorf("config file line %d: unknown setting %q", setting.line, setting.key)
+		}
+		if setting.key == "server-dir" {
+			continue
+		}
+
+		if flag.value {
+			if err := flag.apply(setting.value); err != nil {
+				return fmt.Errorf("config file line %d: %w", setting.line, err)
+			}
+			continue
+		}
+
+		switch strings.ToLower(setting.value) {
+		case "true", "yes", "on", "1":
+			if err := flag.apply(""); err != nil {
+				return fmt.Errorf("config file line %d: %w", setting.line, err)
+			}
+		case "false", "no", "off", "0":
+		default:
+			return fmt.Errorf("config file line %d: %s expects true or false, got %q", setting.line, setting.key, setting.value)
+		}
+	}
+
+	return nil
+}
+
+// profileNames lists the profiles in the config file for error messages
+func (cfg *configFile) profileNames() string {
+	if len(cfg.profiles) == 0 {
+		return "none"
+	}
+	return strings.Join(slices.Sorted(maps.Keys(cfg.profiles)), ", ")
+}
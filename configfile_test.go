@@ -0,0 +1,103 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testConfigFile = `
+# shared settings
+host = mc.example.com
+silent
+
+[default]
+password = "default pass"
+
+[profiles.creative]
+host = creative.example.com
+password = 'creative pass'
+no-color = false
+`
+
+func TestParseConfigFile(t *testing.T) {
+	cfg, err := parseConfigFile(strings.NewReader(testConfigFile))
+	if err != nil {
+		t.Fatalf("parseConfigFile: %v", err)
+	}
+
+	wantDefaults := []configSetting{
+		{"host", "mc.example.com", 3},
+		{"silent", "true", 4},
+	}
+	if !slices.Equal(cfg.defaults, wantDefaults) {
+		t.Errorf("defaults = %v, want %v", cfg.defaults, wantDefaults)
+	}
+
+	wantCreative := []configSetting{
+		{"host", "creative.example.com", 10},
+		{"password", "creative pass", 11},
+		{"no-color", "false", 12},
+	}
+	if !slices.Equal(cfg.profiles["creative"], wantCreative) {
+		t.Errorf("creative = %v, want %v", cfg.profiles["creative"], wantCreative)
+	}
+}
+
+func TestParseConfigFileErrors(t *testing.T) {
+	for _, text := range []string{"[creative", "[]"} {
+		if _, err := parseConfigFile(strings.NewReader(text)); err == nil {
+			t.Errorf("parseConfigFile(%q) succeeded, want an error", text)
+		}
+	}
+}
+
+func TestConfigFileApply(t *testing.T) {
+	cfg, err := parseConfigFile(strings.NewReader(testConfigFile))
+	if err != nil {
+		t.Fatalf("parseConfigFile: %v", err)
+	}
+
+	// apply applies the settings of a profile like applyConfigFile does
+	apply := func(cfg *configFile, profile string, flags []cliFlag) error {
+		settings, err := cfg.settings(profile)
+		if err != nil {
+			return err
+		}
+		return applySettings(settings, flags)
+	}
+
+	tests := []struct {
+		profile  string
+		host     []string
+		password []string
+	}{
+		{"", []string{"mc.example.com"}, []string{"default pass"}},
+		{"creative", []string{"mc.example.com", "creative.example.com"}, []string{"creative pass"}},
+	}
+	for _, tt := range tests {
+		values := map[string][]string{}
+		if err := apply(cfg, tt.profile, testFlags(values)); err != nil {
+			t.Fatalf("apply(%q): %v", tt.profile, err)
+		}
+		if !slices.Equal(values["host"], tt.host) || !slices.Equal(values["password"], tt.password) {
+			t.Errorf("apply(%q) applied %q", tt.profile, values)
+		}
+		if len(values["silent"]) != 1 || len(values["no-color"]) != 0 {
+			t.Errorf("apply(%q) applied %q", tt.profile, values)
+		}
+	}
+
+	if err := apply(cfg, "survival", testFlags(map[string][]string{})); err == nil {
+		t.Error("apply accepted an unknown profile")
+	}
+	for _, text := range []string{"nope = 1", "config = other", "silent = maybe"} {
+		cfg, err := parseConfigFile(strings.NewReader(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := apply(cfg, "", testFlags(map[string][]string{})); err == nil {
+			t.Errorf("apply accepted %q", text)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cliFlag describes a command line option with a short and/or long name
+type cliFlag struct {
+	short string // single letter used as -x (optional)
+	long  string // name used as --name (optional)
+	value bool   // whether the option takes a value
+	apply func(value string) error
+}
+
+// name returns the option as the user would write it, for error messages
+func (f *cliFlag) name() string {
+	if f.long != "" {
+		return "--" + f.long
+	}
+	return "-" + f.short
+}
+
+// parseArgs applies the options in args and returns the positional
+// arguments. Supported forms are -H host, -Hhost, -H=host, --host host,
+// --host=host and combined boolean short options such as -sc. Everything
+// after a "--" terminator is treated as positional, as is any argument
+// that can't be an option (see isOption).
+func parseArgs(args []string, flags []cliFlag) ([]string, error) {
+	var positional []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--":
+			return append(positional, args[i+1:]...), nil
+
+		case !isOption(arg):
+			positional = append(positional, arg)
+
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			flag := findLongFlag(flags, name)
+			if flag == nil {
+				return nil, fmt.Errorf("unknown option: --%s", name)
+			}
+
+			if flag.value && !hasValue {
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("option %s requires a value", flag.name())
+				}
+				value = args[i+1]
+				i++
+			} else if !flag.value && hasValue {
+				return nil, fmt.Errorf("option %s does not take a value", flag.name())
+			}
+
+			if err := flag.apply(value); err != nil {
+				return nil, err
+			}
+
+		default:
+			consumed, err := applyShortFlags(args, i, flags)
+			if err != nil {
+				return nil, err
+			}
+			i += consumed
+		}
+	}
+
+	return positional, nil
+}
+
+// isOption reports whether arg looks like an option. Arguments that don't
+// start with a dash, a lone "-", arguments with whitespace in the option
+// name (such as a quoted "-1 warning") and negative numbers are command
+// text instead.
+func isOption(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' {
+		return false
+	}
+	if name, _, _ := strings.Cut(arg, "="); strings.ContainsAny(name, " \t\n") {
+		return false
+	}
+	return arg[1] < '0' || arg[1] > '9'
+}
+
+// applyShortFlags applies the short options in args[i], such as -s, -sc,
+// -Hhost or -H=host, and returns how many following arguments were used
+// as the option value
+func applyShortFlags(args []string, i int, flags []cliFlag) (int, error) {
+	arg := args[i]
+
+	for j := 1; j < len(arg); j++ {
+		flag := findShortFlag(flags, arg[j:j+1])
+		if flag == nil {
+			return 0, fmt.Errorf("unknown option: -%s", arg[j:j+1])
+		}
+
+		if !flag.value {
+			if err := flag.apply(""); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		// The value is the rest of this argument or the next argument
+		if rest := strings.TrimPrefix(arg[j+1:], "="); rest != "" {
+			return 0, flag.apply(rest)
+		}
+		if i+1 >= len(args) {
+			return 0, fmt.Errorf("option %s requires a value", flag.name())
+		}
+		return 1, flag.apply(args[i+1])
+	}
+
+	return 0, nil
+}
+
+func findLongFlag(flags []cliFlag, name string) *cliFlag {
+	for i := range flags {
+		if flags[i].long != "" && flags[i].long == name {
+			return &flags[i]
+		}
+	}
+	return nil
+}
+
+func findShortFlag(flags []cliFlag, name string) *cliFlag {
+	for i := range flags {
+		if flags[i].short != "" && flags[i].short == name {
+			return &flags[i]
+		}
+	}
+	return nil
+}
+
+// findOptionValue returns the last value given for the option with the
+// long name in args, in any of the forms parseArgs accepts, without
+// applying any options. It's used for options that affect how the others
+// are applied.
+func findOptionValue(args []string, flags []cliFlag, long string) (value string, found bool) {
+	probe := make([]cliFlag, len(flags))
+	for i, flag := range flags {
+		flag.apply = func(string) error { return nil }
+		if flag.long == long {
+			flag.apply = func(v string) error {
+				value, found = v, true
+				return nil
+			}
+		}
+		probe[i] = flag
+	}
+
+	// Errors are reported when the options are parsed for real
+	parseArgs(args, probe)
+	return value, found
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// testFlags returns options like the real ones that record what they're
+// given in values
+func testFlags(values map[string][]string) []cliFlag {
+	record := func(name string) func(string) error {
+		return func(v string) error {
+			values[name] = append(values[name], v)
+			return nil
+		}
+	}
+	return []cliFlag{
+		{short: "H", long: "host", value: true, apply: record("host")},
+		{short: "p", long: "password", value: true, apply: record("password")},
+		{short: "s", long: "silent", apply: record("silent")},
+		{short: "c", long: "no-color", apply: record("no-color")},
+		{long: "config", value: true, apply: record("config")},
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		values     map[string][]string
+		positional []string
+	}{
+		{
+			name:   "long and short forms",
+			args:   []string{"--host", "a", "--host=b", "-H", "c", "-Hd", "-H=e"},
+			values: map[string][]string{"host": {"a", "b", "c", "d", "e"}},
+		},
+		{
+			name:       "combined short options",
+			args:       []string{"-sc", "list"},
+			values:     map[string][]string{"silent": {""}, "no-color": {""}},
+			positional: []string{"list"},
+		},
+		{
+			name:       "short option with value last",
+			args:       []string{"-sp", "pw", "list"},
+			values:     map[string][]string{"silent": {""}, "password": {"pw"}},
+			positional: []string{"list"},
+		},
+		{
+			name:       "terminator",
+			args:       []string{"-s", "--", "-s", "--host"},
+			values:     map[string][]string{"silent": {""}},
+			positional: []string{"-s", "--host"},
+		},
+		{
+			name:       "command text that looks like options",
+			args:       []string{"-", "-1", "-5 warnings", "say -s"},
+			values:     map[string][]string{},
+			positional: []string{"-", "-1", "-5 warnings", "say -s"},
+		},
+		{
+			name:       "dash command after the terminator",
+			args:       []string{"-p", "pw", "--", "say -1 warning"},
+			values:     map[string][]string{"password": {"pw"}},
+			positional: []string{"say -1 warning"},
+		},
+		{
+			name:       "value that looks like an option",
+			args:       []string{"-p", "-secret", "list"},
+			values:     map[string][]string{"password": {"-secret"}},
+			positional: []string{"list"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := map[string][]string{}
+			positional, err := parseArgs(tt.args, testFlags(values))
+			if err != nil {
+				t.Fatalf("parseArgs: %v", err)
+			}
+			if !slices.Equal(positional, tt.positional) {
+				t.Errorf("positional = %q, want %q", positional, tt.positional)
+			}
+			for name, want := range tt.values {
+				if !slices.Equal(values[name], want) {
+					t.Errorf("%s = %q, want %q", name, values[name], want)
+				}
+			}
+			if len(values) != len(tt.values) {
+				t.Errorf("options applied: %q, want %q", values, tt.values)
+			}
+		})
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"--nope"},
+		{"-x"},
+		{"--host"},
+		{"-H"},
+		{"--silent=yes"},
+	} {
+		if _, err := parseArgs(args, testFlags(map[string][]string{})); err == nil {
+			t.Errorf("parseArgs(%q) succeeded, want an error", args)
+		}
+	}
+}
+
+func TestFindOptionValue(t *testing.T) {
+	values := map[string][]string{}
+	flags := testFlags(values)
+
+	value, found := findOptionValue([]string{"-s", "--config", "a", "--config=b", "list"}, flags, "config")
+	if !found || value != "b" {
+		t.Errorf("findOptionValue = %q, %v, want %q, true", value, found, "b")
+	}
+	if len(values) != 0 {
+		t.Errorf("findOptionValue applied options: %q", values)
+	}
+
+	if _, found := findOptionValue([]string{"--", "--config", "a"}, flags, "config"); found {
+		t.Error("findOptionValue found an option after the terminator")
+	}
+}
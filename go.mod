@@ -2,6 +2,10 @@ module mcrcon-go
 
 go 1.25.5
 
-require github.com/chzyer/readline v1.5.1
+require (
+	github.com/chzyer/readline v1.5.1
+	golang.org/x/net v0.47.0
+	golang.org/x/text v0.31.0
+)
 
-require golang.org/x/sys v0.0.0-20220310020820-b874c991c1a5 // indirect
+require golang.org/x/sys v0.38.0 // indirect
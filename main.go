@@ -1,137 +1,898 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
+	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
+	"time"
 
-    "mcrcon-go/mcrcon"
+	"mcrcon-go/mcrcon"
 )
 
+// defaultAnnounceAt are the --announce offsets used without --at
+var defaultAnnounceAt = []time.Duration{60 * time.Second, 30 * time.Second, 10 * time.Second, 5 * time.Second}
+
+// passwordCommandTimeout limits how long --password-command may run, which
+// leaves time for helpers that prompt to unlock a secret store
+const passwordCommandTimeout = 60 * time.Second
+
+// cliOptions holds settings that only affect the command line tool
+type cliOptions struct {
+	commands      []string
+	execCommands  []string
+	fileCommands  []string
+	ping          bool
+	info          bool
+	players       bool
+	rawSend       bool            // whether --raw-type was given
+	rawType       int32           // packet type for --raw-type
+	rawBody       string          // packet body for --raw-body
+	announce      string          // countdown message for --announce
+	announceAt    []time.Duration // offsets before the commands to announce at
+	waitForServer time.Duration   // how long to wait for the server to accept connections
+	interval      time.Duration   // run the commands this often, from --interval
+	reconnect     bool            // reconnect before each --interval cycle, from --cycle-reconnect
+	pipeline      bool            // send all commands before reading responses
+	repeat        bool            // whether --count was given
+	count         int             // times to run the commands, 0 for no limit
+	stdin         bool
+	emptyMarker   bool // whether --empty-marker was given
+	passwordStdin bool
+	passwordCmd   string // shell command printing the password, from --password-command
+	noTerminal    bool
+	listen        string
+	metricsFile   string
+}
+
 func main() {
-	config := parseFlags()
+	config, opts := parseFlags()
 
-	// Handle interrupt signals gracefully
-	setupSignalHandler()
+	// exit writes the metrics file before exiting, so that failed
+	// connections, rejected passwords and interrupted runs are recorded
+	exit := func(code int) {
+		if opts.metricsFile != "" {
+			if err := config.Metrics.WriteTextfile(opts.metricsFile); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+		}
+		os.Exit(code)
+	}
 
-	client, err := mcrcon.NewRCONClient(config)
+	// --wait-for-server connects and authenticates in one go
+	var client *mcrcon.RCONClient
+	var err error
+	if opts.waitForServer > 0 {
+		client, err = mcrcon.WaitForServer(config, opts.waitForServer)
+		if errors.Is(err, mcrcon.ErrAuthFailed) {
+			fmt.Fprintln(os.Stderr, "Authentication failed: wrong rcon password")
+			exit(2)
+		}
+	} else {
+		client, err = mcrcon.NewRCONClient(config)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Connection failed: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	defer client.Close()
 
-	// Authenticate
-	if err := client.Authenticate(); err != nil {
-		fmt.Fprintf(os.Stderr, "Authentication failed: %v\n", err)
-		os.Exit(1)
+	// Handle interrupt signals gracefully. A scheduled run stops after
+	// the current cycle on the first signal.
+	var stop chan struct{}
+	if opts.interval > 0 {
+		stop = make(chan struct{})
+	}
+	signalStatus := setupSignalHandler(client, opts.repeat || opts.interval > 0, stop, exit)
+
+	// Authenticate, unless WaitForServer already did
+	if opts.waitForServer == 0 {
+		if err := client.Authenticate(); err != nil {
+			if errors.Is(err, mcrcon.ErrAuthFailed) {
+				fmt.Fprintln(os.Stderr, "Authentication failed: wrong rcon password")
+				exit(2)
+			}
+			fmt.Fprintf(os.Stderr, "Authentication failed: %v\n", err)
+			exit(1)
+		}
 	}
 
 	// Run commands or terminal mode
 	var exitCode int
-	if config.TerminalMode {
+	switch {
+	case opts.rawSend:
+		exitCode = runRawSend(client, config, opts.rawType, opts.rawBody)
+	case opts.ping:
+		exitCode = runPing(client)
+	case opts.info:
+		exitCode = runInfo(client)
+	case opts.players:
+		exitCode = runPlayers(client)
+	case opts.listen != "":
+		exitCode = runBridge(client, opts.listen)
+	case opts.stdin:
+		exitCode = client.RunReader(os.Stdin)
+	case config.TerminalMode:
 		exitCode = client.RunTerminalMode()
-	} else {
-		exitCode = client.RunCommands(os.Args[1:])
+	case opts.announce != "":
+		exitCode = client.RunCountdown(opts.announce, opts.announceAt, opts.commands)
+	case opts.interval > 0:
+		exitCode = client.RunScheduled(opts.commands, opts.interval, opts.reconnect, stop)
+	case opts.pipeline:
+		exitCode = client.RunPipelined(opts.commands)
+	case opts.repeat:
+		exitCode = client.RunRepeated(opts.commands, opts.count)
+	default:
+		exitCode = client.RunCommands(opts.commands)
 	}
 
-	os.Exit(exitCode)
+	if status := signalStatus(); status != 0 {
+		exitCode = status
+	}
+
+	exit(exitCode)
 }
 
-// parseFlags parses command line flags and environment variables
-func parseFlags() *mcrcon.Config {
+// parseFlags parses command line flags and environment variables.
+// Commands run in this order: --exec commands, commands read from a
+// command file, then positional commands.
+func parseFlags() (*mcrcon.Config, *cliOptions) {
 	config := &mcrcon.Config{
-		Host: getEnvOrDefault("MCRCON_HOST", mcrcon.DefaultHost),
-		Port: getEnvOrDefault("MCRCON_PORT", mcrcon.DefaultPort),
+		Host:     getEnvOrDefault("MCRCON_HOST", mcrcon.DefaultHost),
+		Port:     os.Getenv("MCRCON_PORT"),
 		Password: os.Getenv("MCRCON_PASS"),
+		Proxy:    os.Getenv("MCRCON_PROXY"),
+	}
+
+	opts := &cliOptions{announceAt: defaultAnnounceAt}
+
+	// server.properties and then config file settings override
+	// environment variables and are overridden by command line options
+	flags := cliFlags(config, opts)
+	if err := applyConfigFile(os.Args[1:], flags, config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	commands, err := parseArgs(os.Args[1:], flags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Try 'mcrcon -h' for help.")
+		os.Exit(1)
+	}
+
+	// Without a port, --srv looks it up; otherwise use the default
+	if config.Port == "" && !config.SRV {
+		config.Port = mcrcon.DefaultPort
+	}
+
+	if err := config.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	commands, err = applySubcommand(commands, config, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Try 'mcrcon -h' for help.")
+		os.Exit(1)
 	}
 
-	// Simple flag parsing
-	var commands []string
-	for i := 1; i < len(os.Args); i++ {
-		arg := os.Args[i]
+	opts.commands = append(opts.execCommands, opts.fileCommands...)
+	opts.commands = append(opts.commands, commands...)
+
+	// Enable terminal mode if no commands given
+	if len(opts.commands) == 0 && !opts.ping && !opts.info && !opts.players && !opts.rawSend && opts.announce == "" && !opts.stdin && opts.listen == "" {
+		config.TerminalMode = true
+	}
 
-		if !strings.HasPrefix(arg, "-") {
-			commands = append(commands, arg)
-			continue
+	if config.TerminalMode && opts.noTerminal {
+		fmt.Fprintln(os.Stderr, "Error: no commands given and --no-terminal prevents terminal mode")
+		fmt.Fprintln(os.Stderr, "Try 'mcrcon -h' for help.")
+		os.Exit(1)
+	}
+
+	if opts.passwordStdin {
+		if config.TerminalMode || opts.stdin {
+			fmt.Fprintln(os.Stderr, "Error: --password-stdin uses standard input, so commands must be given as arguments, with -e or with -f")
+			os.Exit(1)
+		}
+		password, err := readPasswordStdin()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		config.Password = password
+	}
+
+	if opts.passwordCmd != "" && !config.DryRun {
+		password, err := runPasswordCommand(opts.passwordCmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
+		config.Password = password
+	}
+
+	if config.Password == "" && !config.DryRun {
+		fmt.Fprintln(os.Stderr, "You must provide password (-p password).")
+		fmt.Fprintln(os.Stderr, "Try 'mcrcon -h' for help.")
+		os.Exit(1)
+	}
+
+	// Show that a command without output went through in terminal mode
+	if config.TerminalMode && !opts.emptyMarker {
+		config.EmptyMarker = mcrcon.DefaultEmptyMarker
+	}
+
+	return config, opts
+}
+
+// applySubcommand handles a subcommand given as the first positional
+// argument and returns the remaining arguments, which are commands:
+//
+//	exec <commands...>  run commands (the default without a subcommand)
+//	shell               terminal mode
+//	ping                measure round-trip time (like --ping)
+//	info                print the server version (like --info)
+func applySubcommand(args []string, config *mcrcon.Config, opts *cliOptions) ([]string, error) {
+	if len(args) == 0 {
+		return args, nil
+	}
 
-		switch arg {
-		case "-H":
-			if i+1 < len(os.Args) {
-				config.Host = os.Args[i+1]
-				i++
-			}
-		case "-P":
-			if i+1 < len(os.Args) {
-				config.Port = os.Args[i+1]
-				i++
-			}
-		case "-p":
-			if i+1 < len(os.Args) {
-				config.Password = os.Args[i+1]
-				i++
-			}
-		case "-w":
-			if i+1 < len(os.Args) {
-				wait, err := parseWaitSeconds(os.Args[i+1])
+	name, rest := args[0], args[1:]
+	switch name {
+	case "exec":
+		if len(rest) == 0 && len(opts.execCommands) == 0 && len(opts.fileCommands) == 0 {
+			return nil, errors.New("exec requires at least one command")
+		}
+		return rest, nil
+	case "shell":
+		config.TerminalMode = true
+	case "ping":
+		opts.ping = true
+	case "info":
+		opts.info = true
+	default:
+		return args, nil
+	}
+
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%s takes no commands, got %q", name, rest[0])
+	}
+	return rest, nil
+}
+
+// cliFlags returns the command line options, which store their values in
+// config and opts
+func cliFlags(config *mcrcon.Config, opts *cliOptions) []cliFlag {
+	return []cliFlag{
+		{short: "H", long: "host", value: true, apply: func(v string) error {
+			config.Host, config.Port = splitHostFlag(v, config.Port)
+			return nil
+		}},
+		// --config, --profile and --server-dir are applied before the
+		// other options by applyConfigFile
+		{long: "config", value: true, apply: func(string) error { return nil }},
+		{long: "server-dir", value: true, apply: func(string) error { return nil }},
+		{short: "S", long: "profile", value: true, apply: func(string) error { return nil }},
+		{short: "P", long: "port", value: true, apply: func(v string) error {
+			config.Port = v
+			return nil
+		}},
+		{short: "p", long: "password", value: true, apply: func(v string) error {
+			config.Password = v
+			return nil
+		}},
+		{long: "password-stdin", apply: func(string) error {
+			opts.passwordStdin = true
+			return nil
+		}},
+		{long: "password-command", value: true, apply: func(v string) error {
+			opts.passwordCmd = v
+			return nil
+		}},
+		{short: "w", long: "wait", value: true, apply: func(v string) error {
+			wait, err := parseWait(v)
+			if err != nil {
+				return err
+			}
+			config.Wait = wait
+			return nil
+		}},
+		{long: "jitter", value: true, apply: func(v string) error {
+			jitter, err := parseWait(v)
+			if err != nil {
+				return fmt.Errorf("invalid jitter: %v", err)
+			}
+			config.Jitter = jitter
+			return nil
+		}},
+		{short: "e", long: "exec", value: true, apply: func(v string) error {
+			opts.execCommands = append(opts.execCommands, v)
+			return nil
+		}},
+		{short: "f", long: "command-file", value: true, apply: func(v string) error {
+			cmds, err := mcrcon.ReadCommandFile(v)
+			if err != nil {
+				return err
+			}
+			opts.fileCommands = append(opts.fileCommands, cmds...)
+			return nil
+		}},
+		{long: "prefix", value: true, apply: func(v string) error {
+			config.CommandPrefix = v
+			return nil
+		}},
+		{long: "var", value: true, apply: func(v string) error {
+			name, value, ok := strings.Cut(v, "=")
+			if !ok || name == "" {
+				return fmt.Errorf("invalid variable %q: expected name=value", v)
+			}
+			enableVars(config)
+			config.Vars[name] = value
+			return nil
+		}},
+		{long: "allow-unset", apply: func(string) error {
+			enableVars(config)
+			config.AllowUnset = true
+			return nil
+		}},
+		{long: "stdin", apply: func(string) error {
+			opts.stdin = true
+			return nil
+		}},
+		{long: "count", value: true, apply: func(v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid count: %s", v)
+			}
+			opts.repeat, opts.count = true, n
+			return nil
+		}},
+		{long: "announce", value: true, apply: func(v string) error {
+			opts.announce = v
+			return nil
+		}},
+		{long: "at", value: true, apply: func(v string) error {
+			opts.announceAt = nil
+			for _, field := range strings.Split(v, ",") {
+				offset, err := parseWait(strings.TrimSpace(field))
 				if err != nil {
-					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-					os.Exit(1)
+					return fmt.Errorf("invalid --at offset: %v", err)
 				}
-				config.WaitSeconds = wait
-				i++
+				opts.announceAt = append(opts.announceAt, offset)
+			}
+			return nil
+		}},
+		{long: "wait-for-server", value: true, apply: func(v string) error {
+			timeout, err := parseWait(v)
+			if err != nil {
+				return fmt.Errorf("invalid --wait-for-server timeout: %v", err)
+			}
+			opts.waitForServer = timeout
+			return nil
+		}},
+		{long: "interval", value: true, apply: func(v string) error {
+			interval, err := parseWait(v)
+			if err != nil {
+				return fmt.Errorf("invalid interval: %v", err)
+			}
+			opts.interval = interval
+			return nil
+		}},
+		{long: "cycle-reconnect", apply: func(string) error {
+			opts.reconnect = true
+			return nil
+		}},
+		{long: "pipeline", apply: func(string) error {
+			opts.pipeline = true
+			return nil
+		}},
+		{long: "max-commands", value: true, apply: func(v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid command limit: %s", v)
+			}
+			config.MaxCommands = n
+			return nil
+		}},
+		{short: "k", long: "keep-going", apply: func(string) error {
+			config.KeepGoing = true
+			return nil
+		}},
+		{long: "fail-on", value: true, apply: func(v string) error {
+			config.FailOn = failOnPattern(v)
+			return nil
+		}},
+		{long: "allow", value: true, apply: func(v string) error {
+			pattern, err := mcrcon.ParseCommandPattern(v)
+			if err != nil {
+				return err
+			}
+			config.Allow = append(config.Allow, pattern)
+			return nil
+		}},
+		{long: "deny", value: true, apply: func(v string) error {
+			pattern, err := mcrcon.ParseCommandPattern(v)
+			if err != nil {
+				return err
+			}
+			config.Deny = append(config.Deny, pattern)
+			return nil
+		}},
+		{long: "request-id", value: true, apply: func(v string) error {
+			id, err := strconv.ParseInt(v, 0, 32)
+			if err != nil {
+				return fmt.Errorf("invalid request ID: %v", err)
+			}
+			if id <= 0 {
+				return fmt.Errorf("invalid request ID %d: must be positive", id)
 			}
-		case "-t":
+			config.RequestID = int32(id)
+			return nil
+		}},
+		{long: "follow", apply: func(string) error {
+			config.Follow = true
+			return nil
+		}},
+		{long: "follow-window", value: true, apply: func(v string) error {
+			window, err := time.ParseDuration(v)
+			if err != nil || window <= 0 {
+				return fmt.Errorf("invalid follow window: %s", v)
+			}
+			config.FollowWindow = window
+			return nil
+		}},
+		{long: "drain", apply: func(string) error {
+			config.Drain = true
+			return nil
+		}},
+		{long: "no-retry", apply: func(string) error {
+			config.NoRetry = true
+			return nil
+		}},
+		{long: "retry-timeouts", value: true, apply: func(v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid retry count: %s", v)
+			}
+			config.TimeoutRetries = n
+			return nil
+		}},
+		{long: "reconnect", apply: func(string) error {
+			config.AutoReconnect = true
+			return nil
+		}},
+		{long: "srv", apply: func(string) error {
+			config.SRV = true
+			return nil
+		}},
+		{long: "wait-for-reconnect", value: true, apply: func(v string) error {
+			timeout, err := time.ParseDuration(v)
+			if err != nil || timeout <= 0 {
+				return fmt.Errorf("invalid reconnect timeout: %s", v)
+			}
+			config.ReconnectWait = timeout
+			return nil
+		}},
+		{long: "big-endian", apply: func(string) error {
+			config.BigEndian = true
+			return nil
+		}},
+		{long: "tls", apply: func(string) error {
+			config.TLS = true
+			return nil
+		}},
+		{long: "tls-insecure", apply: func(string) error {
+			config.TLS = true
+			tlsConfig(config).InsecureSkipVerify = true
+			return nil
+		}},
+		{long: "tls-ca", value: true, apply: func(v string) error {
+			pem, err := os.ReadFile(v)
+			if err != nil {
+				return fmt.Errorf("failed to read CA bundle: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return fmt.Errorf("no certificates found in CA bundle %s", v)
+			}
+			config.TLS = true
+			tlsConfig(config).RootCAs = pool
+			return nil
+		}},
+		{long: "proxy", value: true, apply: func(v string) error {
+			config.Proxy = v
+			return nil
+		}},
+		{long: "charset", value: true, apply: func(v string) error {
+			charset, err := mcrcon.ParseCharset(v)
+			if err != nil {
+				return err
+			}
+			config.Charset = charset
+			return nil
+		}},
+		{long: "unix", value: true, apply: func(v string) error {
+			config.UnixSocket = v
+			return nil
+		}},
+		{long: "source-addr", value: true, apply: func(v string) error {
+			config.SourceAddr = v
+			return nil
+		}},
+		{long: "tcp-keepalive", value: true, apply: func(v string) error {
+			period, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid keep-alive period: %s", v)
+			}
+			if period == 0 {
+				period = -1 // 0 disables keep-alive on the command line
+			}
+			config.KeepAlive = period
+			return nil
+		}},
+		{long: "keepalive-interval", value: true, apply: func(v string) error {
+			interval, err := time.ParseDuration(v)
+			if err != nil || interval <= 0 {
+				return fmt.Errorf("invalid keepalive interval: %s", v)
+			}
+			config.HeartbeatInterval = interval
+			return nil
+		}},
+		{long: "metrics-file", value: true, apply: func(v string) error {
+			opts.metricsFile = v
+			config.Metrics = &mcrcon.Metrics{}
+			return nil
+		}},
+		{long: "verbose", apply: func(string) error {
+			config.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			return nil
+		}},
+		{short: "n", long: "dry-run", apply: func(string) error {
+			config.DryRun = true
+			return nil
+		}},
+		{long: "ping", apply: func(string) error {
+			opts.ping = true
+			return nil
+		}},
+		{long: "info", apply: func(string) error {
+			opts.info = true
+			return nil
+		}},
+		{long: "players", apply: func(string) error {
+			opts.players = true
+			return nil
+		}},
+		{long: "listen", value: true, apply: func(v string) error {
+			opts.listen = v
+			return nil
+		}},
+		{short: "t", long: "terminal", apply: func(string) error {
 			config.TerminalMode = true
-		case "-s":
+			return nil
+		}},
+		{long: "quiet-auth", apply: func(string) error {
+			config.QuietAuth = true
+			return nil
+		}},
+		{long: "no-prompt", apply: func(string) error {
+			config.NoPrompt = true
+			return nil
+		}},
+		{long: "no-stop-break", apply: func(string) error {
+			config.NoStopBreak = true
+			return nil
+		}},
+		{long: "stop-command", value: true, apply: func(v string) error {
+			config.StopCommand = v
+			return nil
+		}},
+		{long: "no-terminal", apply: func(string) error {
+			opts.noTerminal = true
+			return nil
+		}},
+		{short: "s", long: "silent", apply: func(string) error {
 			config.SilentMode = true
-		case "-c":
+			return nil
+		}},
+		{short: "c", long: "no-color", apply: func(string) error {
 			config.DisableColors = true
-		case "-r":
+			return nil
+		}},
+		{long: "ampersand-codes", apply: func(string) error {
+			config.AmpersandCodes = true
+			return nil
+		}},
+		{long: "color-map", value: true, apply: func(v string) error {
+			colorMap, err := mcrcon.ParseColorMap(v)
+			if err != nil {
+				return err
+			}
+			if config.ColorMap == nil {
+				config.ColorMap = make(map[byte]string)
+			}
+			maps.Copy(config.ColorMap, colorMap)
+			return nil
+		}},
+		{long: "empty-marker", value: true, apply: func(v string) error {
+			config.EmptyMarker = v
+			opts.emptyMarker = true
+			return nil
+		}},
+		{long: "format", value: true, apply: func(v string) error {
+			tmpl, err := mcrcon.ParseFormat(v)
+			if err != nil {
+				return err
+			}
+			config.Format = tmpl
+			return nil
+		}},
+		{long: "timing", apply: func(string) error {
+			config.Timing = true
+			return nil
+		}},
+		{long: "echo-command", apply: func(string) error {
+			config.EchoCommand = true
+			return nil
+		}},
+		{long: "max-body-bytes", value: true, apply: func(v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid body size limit: %s", v)
+			}
+			config.MaxBodyBytes = n
+			return nil
+		}},
+		{long: "escape-ansi", apply: func(string) error {
+			config.ColorTags = true
+			return nil
+		}},
+		{long: "oneline", apply: func(string) error {
+			config.OneLine = true
+			return nil
+		}},
+		{long: "output-file", value: true, apply: func(v string) error {
+			f, err := os.OpenFile(v, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+			if err != nil {
+				return fmt.Errorf("failed to open output file: %w", err)
+			}
+			config.Transcript = f
+			return nil
+		}},
+		{short: "r", long: "raw", apply: func(string) error {
 			config.RawOutput = true
-		case "-v":
+			return nil
+		}},
+		{long: "raw-hex", apply: func(string) error {
+			config.RawHex = true
+			return nil
+		}},
+		{long: "raw-type", value: true, apply: func(v string) error {
+			t, err := strconv.ParseInt(v, 0, 32)
+			if err != nil {
+				return fmt.Errorf("invalid packet type: %v", err)
+			}
+			opts.rawSend, opts.rawType = true, int32(t)
+			return nil
+		}},
+		{long: "raw-body", value: true, apply: func(v string) error {
+			opts.rawBody = v
+			return nil
+		}},
+		{long: "hexdump-sent", apply: func(string) error {
+			config.HexdumpSent = true
+			return nil
+		}},
+		{short: "v", long: "version", apply: func(string) error {
 			fmt.Printf("%s %s\n", mcrcon.AppName, mcrcon.Version)
 			fmt.Println("https://github.com/Tiiffi/mcrcon")
 			os.Exit(0)
-		case "-h":
+			return nil
+		}},
+		{short: "h", long: "help", apply: func(string) error {
 			mcrcon.PrintHelp()
 			os.Exit(0)
-		default:
-			fmt.Fprintf(os.Stderr, "Unknown option: %s\n", arg)
-			fmt.Println("Try 'mcrcon -h' for help.")
-			os.Exit(1)
-		}
+			return nil
+		}},
 	}
+}
 
-	if config.Password == "" {
-		fmt.Println("You must provide password (-p password).")
-		fmt.Println("Try 'mcrcon -h' for help.")
-		os.Exit(1)
+// tlsConfig returns config.TLSConfig, creating it if needed
+func tlsConfig(config *mcrcon.Config) *tls.Config {
+	if config.TLSConfig == nil {
+		config.TLSConfig = &tls.Config{}
 	}
+	return config.TLSConfig
+}
 
-	// Enable terminal mode if no commands given
-	if len(commands) == 0 {
-		config.TerminalMode = true
+// runPing measures the round-trip time of a single command
+func runPing(client *mcrcon.RCONClient) int {
+	latency, err := client.Ping()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ping failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("%.2f ms\n", float64(latency.Microseconds())/1000)
+	return 0
+}
+
+// runRawSend sends a single packet of the given type and dumps the
+// response as received
+func runRawSend(client *mcrcon.RCONClient, config *mcrcon.Config, packetType int32, body string) int {
+	config.RawHex = true
+	if _, err := client.SendRaw(packetType, body); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runInfo prints the server brand and version
+func runInfo(client *mcrcon.RCONClient) int {
+	info, err := client.ServerInfo()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Println(info)
+	return 0
+}
+
+// runPlayers prints the players online as JSON
+func runPlayers(client *mcrcon.RCONClient) int {
+	online, max, names, err := client.ListPlayers()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	out, _ := json.Marshal(struct {
+		Online  int      `json:"online"`
+		Max     int      `json:"max"`
+		Players []string `json:"players"`
+	}{online, max, names})
+	fmt.Println(string(out))
+	return 0
+}
+
+// enableVars turns on variable expansion, which is off unless --var or
+// --allow-unset is given so that commands containing $ are sent as typed
+func enableVars(config *mcrcon.Config) {
+	if config.Vars == nil {
+		config.Vars = make(map[string]string)
+	}
+}
+
+// runBridge serves the HTTP bridge on addr until it fails. Addresses
+// without a host, such as ":8080", bind to localhost only.
+func runBridge(client *mcrcon.RCONClient, addr string) int {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid listen address: %v\n", err)
+		return 1
+	}
+	if host == "" {
+		addr = net.JoinHostPort("127.0.0.1", port)
+	}
+
+	fmt.Fprintf(os.Stderr, "Listening on http://%s\n", addr)
+	if err := http.ListenAndServe(addr, mcrcon.NewBridgeHandler(client)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// readPasswordStdin reads the password from the first line of standard
+// input, which must not be a terminal
+func readPasswordStdin() (string, error) {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return "", errors.New("--password-stdin requires the password to be piped to standard input")
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read password from standard input: %w", err)
 	}
 
-	return config
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("no password on standard input")
+	}
+	return password, nil
 }
 
-func parseWaitSeconds(s string) (uint, error) {
-	val, err := strconv.Atoi(s)
+// runPasswordCommand runs command with the shell and returns the first
+// line of its output, trimmed, like git's askpass helpers. The helper's
+// stdin and stderr are the terminal's, so it can prompt for a passphrase.
+func runPasswordCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("--password-command timed out after %s", passwordCommandTimeout)
+	}
 	if err != nil {
-		return 0, fmt.Errorf("invalid wait value: %v", err)
+		return "", fmt.Errorf("--password-command failed: %w", err)
 	}
 
-	if val <= 0 || val > mcrcon.MaxWaitTime {
-		return 0, fmt.Errorf("wait value out of range (1-%d)", mcrcon.MaxWaitTime)
+	line, _, _ := strings.Cut(string(out), "\n")
+	password := strings.TrimSpace(line)
+	if password == "" {
+		return "", errors.New("--password-command printed no password")
 	}
+	return password, nil
+}
 
-	return uint(val), nil
+// splitHostFlag splits a bracketed IPv6 address with a port ([::1]:25575)
+// into host and port. Any other value is returned unchanged as the host.
+func splitHostFlag(value, port string) (string, string) {
+	if strings.HasPrefix(value, "[") {
+		if host, p, err := net.SplitHostPort(value); err == nil {
+			return host, p
+		}
+	}
+	return value, port
+}
+
+// parseWait parses a wait duration such as 500ms or 2s. A bare number is
+// a number of seconds, as in earlier versions.
+func parseWait(s string) (time.Duration, error) {
+	wait, err := time.ParseDuration(s)
+	if seconds, aerr := strconv.Atoi(s); aerr == nil {
+		wait, err = time.Duration(seconds)*time.Second, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid wait value: %s", s)
+	}
+
+	if wait <= 0 {
+		return 0, fmt.Errorf("wait value must be positive: %s", s)
+	}
+
+	return wait, nil
+}
+
+// failOnPattern compiles a --fail-on value so that responses containing
+// it literally match as well as those matching it as a regular
+// expression; values that aren't valid regular expressions, such as
+// "x(", only match literally
+func failOnPattern(v string) *regexp.Regexp {
+	literal := regexp.QuoteMeta(v)
+	if _, err := regexp.Compile(v); err != nil {
+		return regexp.MustCompile(literal)
+	}
+	return regexp.MustCompile(literal + "|(?:" + v + ")")
 }
 
 func getEnvOrDefault(key, defaultValue string) string {
@@ -141,13 +902,40 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
-func setupSignalHandler() {
+// setupSignalHandler closes the connection cleanly on SIGINT or SIGTERM,
+// since os.Exit skips deferred calls, and exits with the conventional
+// 128+signal status (130 for SIGINT, 143 for SIGTERM) through exit. With
+// graceful set, it only closes the client, letting the current run finish
+// and print its summary; the returned function then reports the status for
+// the signal received, or 0 if there was none. A non-nil stop is closed on
+// the first signal instead, so a scheduled run can finish its cycle, and a
+// second signal is handled as above.
+func setupSignalHandler(client *mcrcon.RCONClient, graceful bool, stop chan<- struct{}, exit func(code int)) func() int {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+	var status atomic.Int32
 	go func() {
-		<-sigChan
-		fmt.Println("\nDisconnecting...")
-		os.Exit(0)
+		sig := <-sigChan
+		code := 128 + int(syscall.SIGINT)
+		if sig == syscall.SIGTERM {
+			code = 128 + int(syscall.SIGTERM)
+		}
+		status.Store(int32(code))
+
+		if stop != nil {
+			fmt.Fprintln(os.Stderr, "\nStopping after the current cycle (interrupt again to quit now)...")
+			close(stop)
+			<-sigChan
+		}
+
+		fmt.Fprintln(os.Stderr, "\nDisconnecting...")
+		client.Close()
+
+		if !graceful {
+			exit(code)
+		}
 	}()
+
+	return func() int { return int(status.Load()) }
 }
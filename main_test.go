@@ -0,0 +1,309 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"mcrcon-go/mcrcon"
+)
+
+func TestFailOnPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		response string
+		want     bool
+	}{
+		{"Unknown command", "Unknown command. Type help", true},
+		{"Unknown command (see below)", "Unknown command (see below)", true},
+		{"x(", "error in x(1)", true},
+		{"x(", "x", false},
+		{"^No player", "No player was found", true},
+		{"^No player", "Error: No player", false},
+		{"[0-9]+ errors", "3 errors", true},
+	}
+
+	for _, tt := range tests {
+		if got := failOnPattern(tt.pattern).MatchString(tt.response); got != tt.want {
+			t.Errorf("--fail-on %q matching %q = %v, want %v", tt.pattern, tt.response, got, tt.want)
+		}
+	}
+}
+
+func TestSplitHostFlag(t *testing.T) {
+	tests := []struct {
+		value, host, port string
+	}{
+		{"mc.example.com", "mc.example.com", "25575"},
+		{"mc.example.com:25580", "mc.example.com:25580", "25575"}, // only bracketed addresses are split
+		{"::1", "::1", "25575"},
+		{"fe80::1%eth0", "fe80::1%eth0", "25575"},
+		{"[::1]:25580", "::1", "25580"},
+		{"[fe80::1%eth0]:25580", "fe80::1%eth0", "25580"},
+	}
+
+	for _, tt := range tests {
+		host, port := splitHostFlag(tt.value, "25575")
+		if host != tt.host || port != tt.port {
+			t.Errorf("splitHostFlag(%q) = %q, %q, want %q, %q", tt.value, host, port, tt.host, tt.port)
+		}
+	}
+}
+
+func TestSignalClosesConnection(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	client, err := mcrcon.NewRCONClient(&mcrcon.Config{Host: host, Port: port})
+	if err != nil {
+		t.Fatalf("NewRCONClient: %v", err)
+	}
+	defer client.Close()
+
+	server, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	codes := make(chan int, 1)
+	setupSignalHandler(client, false, nil, func(code int) { codes <- code })
+	syscall.Kill(os.Getpid(), syscall.SIGTERM)
+
+	select {
+	case code := <-codes:
+		if code != 143 {
+			t.Errorf("exit status after SIGTERM = %d, want 143", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the signal handler didn't exit")
+	}
+
+	// A clean close sends a FIN, which the server reads as EOF rather than
+	// a connection reset
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := server.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("server read returned %v after the signal, want io.EOF", err)
+	}
+}
+
+func TestCommandOrder(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "commands.txt")
+	if err := os.WriteFile(file, []byte("# maintenance\nsave-off\n\nsave-all\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := os.Args
+	defer func() { os.Args = args }()
+	os.Args = []string{"mcrcon", "-p", "pw", "say hi", "-e", "-1 warning", "-f", file, "--exec=save-on", "list"}
+
+	_, opts := parseFlags()
+
+	// --exec commands first, then the command file, then positional commands
+	want := []string{"-1 warning", "save-on", "save-off", "save-all", "say hi", "list"}
+	if !slices.Equal(opts.commands, want) {
+		t.Errorf("commands = %q, want %q", opts.commands, want)
+	}
+}
+
+func TestParseWait(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"5", 5 * time.Second},
+		{"600", 600 * time.Second},
+		{"900", 15 * time.Minute},
+		{"500ms", 500 * time.Millisecond},
+		{"2s", 2 * time.Second},
+		{"1m30s", 90 * time.Second},
+		{"2h", 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		got, err := parseWait(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("parseWait(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "0", "-1", "0s", "-2s", "soon", "2 s"} {
+		if _, err := parseWait(value); err == nil {
+			t.Errorf("parseWait(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+// withStdin replaces standard input with a pipe holding input until the
+// test ends
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		io.WriteString(w, input)
+		w.Close()
+	}()
+
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+	})
+}
+
+func TestReadPasswordStdin(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"hunter2\n", "hunter2"},
+		{"hunter2\r\n", "hunter2"},
+		{"hunter2", "hunter2"},
+		{"pass word \nsecond line\n", "pass word "},
+	}
+
+	for _, tt := range tests {
+		withStdin(t, tt.input)
+		got, err := readPasswordStdin()
+		if err != nil || got != tt.want {
+			t.Errorf("readPasswordStdin with %q = %q, %v, want %q", tt.input, got, err, tt.want)
+		}
+	}
+
+	for _, input := range []string{"", "\n"} {
+		withStdin(t, input)
+		if _, err := readPasswordStdin(); err == nil {
+			t.Errorf("readPasswordStdin with %q succeeded, want an error", input)
+		}
+	}
+}
+
+// runParseFlags runs parseFlags with args in a child test process, so
+// that it can exit, and returns its exit status and output. The
+// arguments are passed one per line in MCRCON_TEST_ARGS.
+func runParseFlags(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestParseFlagsHelper$")
+	cmd.Env = append(os.Environ(), "MCRCON_TEST_ARGS="+strings.Join(args, "\n"))
+	out, err := cmd.CombinedOutput()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), string(out)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return 0, string(out)
+}
+
+// TestParseFlagsHelper runs parseFlags for runParseFlags
+func TestParseFlagsHelper(t *testing.T) {
+	args, ok := os.LookupEnv("MCRCON_TEST_ARGS")
+	if !ok {
+		t.Skip("run by runParseFlags")
+	}
+	os.Args = append([]string{"mcrcon"}, strings.Split(args, "\n")...)
+	parseFlags()
+}
+
+func TestNoTerminal(t *testing.T) {
+	code, out := runParseFlags(t, "-p", "secret", "--no-terminal", "-w", "5")
+	if code != 1 {
+		t.Errorf("exit status = %d, want 1", code)
+	}
+	if !strings.Contains(out, "--no-terminal prevents terminal mode") {
+		t.Errorf("output doesn't explain the failure:\n%s", out)
+	}
+
+	if code, out := runParseFlags(t, "-p", "secret", "--no-terminal", "list"); code != 0 {
+		t.Errorf("--no-terminal with a command exited with %d:\n%s", code, out)
+	}
+}
+
+func TestInvalidMaxCommands(t *testing.T) {
+	for _, limit := range []string{"-1", "0", "many"} {
+		code, out := runParseFlags(t, "-p", "secret", "--max-commands", limit, "list")
+		if code != 1 || !strings.Contains(out, "invalid command limit: "+limit) {
+			t.Errorf("--max-commands %s: exit status %d, output:\n%s", limit, code, out)
+		}
+	}
+}
+
+func TestApplySubcommand(t *testing.T) {
+	tests := []struct {
+		args     []string
+		commands []string
+		check    func(config *mcrcon.Config, opts *cliOptions) bool
+	}{
+		{[]string{"exec", "list", "save-all"}, []string{"list", "save-all"}, nil},
+		{[]string{"shell"}, nil, func(config *mcrcon.Config, opts *cliOptions) bool { return config.TerminalMode }},
+		{[]string{"ping"}, nil, func(config *mcrcon.Config, opts *cliOptions) bool { return opts.ping }},
+		{[]string{"info"}, nil, func(config *mcrcon.Config, opts *cliOptions) bool { return opts.info }},
+		{[]string{"list", "info"}, []string{"list", "info"}, nil}, // not the first argument
+	}
+
+	for _, tt := range tests {
+		config, opts := &mcrcon.Config{}, &cliOptions{}
+		commands, err := applySubcommand(tt.args, config, opts)
+		if err != nil {
+			t.Errorf("applySubcommand(%q): %v", tt.args, err)
+			continue
+		}
+		if !slices.Equal(commands, tt.commands) {
+			t.Errorf("applySubcommand(%q) = %q, want %q", tt.args, commands, tt.commands)
+		}
+		if tt.check != nil && !tt.check(config, opts) {
+			t.Errorf("applySubcommand(%q) didn't select its mode", tt.args)
+		}
+	}
+
+	for _, args := range [][]string{{"exec"}, {"shell", "list"}, {"ping", "list"}} {
+		if _, err := applySubcommand(args, &mcrcon.Config{}, &cliOptions{}); err == nil {
+			t.Errorf("applySubcommand(%q) succeeded, want an error", args)
+		}
+	}
+}
+
+func TestRunPasswordCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+
+	script := filepath.Join(t.TempDir(), "askpass.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho '  s3cret  '\necho ignored\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	password, err := runPasswordCommand(script)
+	if err != nil {
+		t.Fatalf("runPasswordCommand: %v", err)
+	}
+	if password != "s3cret" {
+		t.Errorf("password = %q, want %q", password, "s3cret")
+	}
+
+	for _, command := range []string{"exit 3", "true"} {
+		if _, err := runPasswordCommand(command); err == nil {
+			t.Errorf("runPasswordCommand(%q) succeeded, want an error", command)
+		}
+	}
+}
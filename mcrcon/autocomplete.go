@@ -1,72 +1,90 @@
 package mcrcon
 
 import (
-    "github.com/chzyer/readline"
+	"strings"
+
+	"github.com/chzyer/readline"
 )
 
-// newCommandCompleter creates an autocompleter for common Minecraft commands
+// DefaultCommands lists the commands offered for tab completion in terminal
+// mode. Each entry is a command optionally followed by subcommands, so
+// "time set day" completes "time", then "set", then "day". Replace or
+// extend it before calling RunTerminalMode to customize completion.
+var DefaultCommands = []string{
+	"help",
+	"list",
+	"say",
+	"tell",
+	"give",
+	"tp",
+	"teleport",
+	"time set day",
+	"time set night",
+	"time set noon",
+	"time set midnight",
+	"time add",
+	"time query",
+	"weather clear",
+	"weather rain",
+	"weather thunder",
+	"gamemode survival",
+	"gamemode creative",
+	"gamemode adventure",
+	"gamemode spectator",
+	"difficulty peaceful",
+	"difficulty easy",
+	"difficulty normal",
+	"difficulty hard",
+	"kill",
+	"kick",
+	"ban",
+	"ban-ip",
+	"pardon",
+	"pardon-ip",
+	"op",
+	"deop",
+	"whitelist add",
+	"whitelist remove",
+	"whitelist list",
+	"whitelist on",
+	"whitelist off",
+	"whitelist reload",
+	"save-all",
+	"save-on",
+	"save-off",
+	"stop",
+	"seed",
+	"setblock",
+	"fill",
+	"clone",
+	"gamerule",
+	"setworldspawn",
+	"defaultgamemode",
+}
+
+// newCommandCompleter creates an autocompleter for the commands in DefaultCommands
 func newCommandCompleter() *readline.PrefixCompleter {
-	return readline.NewPrefixCompleter(
-		readline.PcItem("help"),
-		readline.PcItem("list"),
-		readline.PcItem("say"),
-		readline.PcItem("tell"),
-		readline.PcItem("give"),
-		readline.PcItem("tp"),
-		readline.PcItem("teleport"),
-		readline.PcItem("time",
-			readline.PcItem("set",
-				readline.PcItem("day"),
-				readline.PcItem("night"),
-				readline.PcItem("noon"),
-				readline.PcItem("midnight"),
-			),
-			readline.PcItem("add"),
-			readline.PcItem("query"),
-		),
-		readline.PcItem("weather",
-			readline.PcItem("clear"),
-			readline.PcItem("rain"),
-			readline.PcItem("thunder"),
-		),
-		readline.PcItem("gamemode",
-			readline.PcItem("survival"),
-			readline.PcItem("creative"),
-			readline.PcItem("adventure"),
-			readline.PcItem("spectator"),
-		),
-		readline.PcItem("difficulty",
-			readline.PcItem("peaceful"),
-			readline.PcItem("easy"),
-			readline.PcItem("normal"),
-			readline.PcItem("hard"),
-		),
-		readline.PcItem("kill"),
-		readline.PcItem("kick"),
-		readline.PcItem("ban"),
-		readline.PcItem("ban-ip"),
-		readline.PcItem("pardon"),
-		readline.PcItem("pardon-ip"),
-		readline.PcItem("op"),
-		readline.PcItem("deop"),
-		readline.PcItem("whitelist",
-			readline.PcItem("add"),
-			readline.PcItem("remove"),
-			readline.PcItem("list"),
-			readline.PcItem("on"),
-			readline.PcItem("off"),
-			readline.PcItem("reload"),
-		),
-		readline.PcItem("save-all"),
-		readline.PcItem("save-on"),
-		readline.PcItem("save-off"),
-		readline.PcItem("stop"),
-		readline.PcItem("seed"),
-		readline.PcItem("setblock"),
-		readline.PcItem("fill"),
-		readline.PcItem("clone"),
-		readline.PcItem("gamerule"),
-		readline.PcItem("setworldspawn"),
-		readline.PcItem("defaultgamemode"),
-	)
+	root := readline.NewPrefixCompleter()
+
+	for _, command := range DefaultCommands {
+		node := root
+		for _, word := range strings.Fields(command) {
+			node = completerChild(node, word)
+		}
+	}
+
+	return root
+}
+
+// completerChild returns the child of node completing word, adding it if needed
+func completerChild(node *readline.PrefixCompleter, word string) *readline.PrefixCompleter {
+	for _, child := range node.Children {
+		if pc, ok := child.(*readline.PrefixCompleter); ok && strings.TrimSpace(string(pc.Name)) == word {
+			return pc
+		}
+	}
+
+	child := readline.PcItem(word)
+	node.Children = append(node.Children, child)
+	return child
 }
@@ -0,0 +1,178 @@
+package mcrcon
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// bridgeRequest is the JSON body of a bridge command request
+type bridgeRequest struct {
+	Cmd string `json:"cmd"`
+}
+
+// bridgeResponse is the JSON body of a successful bridge command response
+type bridgeResponse struct {
+	Command  string `json:"command"`
+	Response string `json:"response"`
+}
+
+// bridgeError is the JSON body of a failed bridge request
+type bridgeError struct {
+	Command string `json:"command,omitempty"`
+	Error   string `json:"error"`
+}
+
+// bridge forwards HTTP requests to an authenticated client
+type bridge struct {
+	client *RCONClient
+}
+
+// NewBridgeHandler returns an HTTP handler that exposes an authenticated
+// client. POST /command with a JSON body like {"cmd": "list"} runs the
+// command and responds with {"command": ..., "response": ...}, where the
+// response has color codes stripped, or with {"error": ...} on failure.
+// Concurrent requests share the connection; the client serializes them.
+//
+// Commands go through the same prefix, variables, Allow and Deny patterns,
+// metrics and transcript as ExecuteCommand. To keep web pages the admin
+// visits from driving the server, requests must be application/json, must
+// not come from another origin and must address the listener by its own
+// IP address, or by localhost when it is a loopback address.
+func NewBridgeHandler(client *RCONClient) http.Handler {
+	b := &bridge{client: client}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/command", b.handleCommand)
+	return mux
+}
+
+func (b *bridge) handleCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeBridgeJSON(w, http.StatusMethodNotAllowed, bridgeError{Error: "method not allowed"})
+		return
+	}
+
+	if err := checkBridgeRequest(r); err != nil {
+		writeBridgeJSON(w, err.status, bridgeError{Error: err.message})
+		return
+	}
+
+	var req bridgeRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		writeBridgeJSON(w, http.StatusBadRequest, bridgeError{Error: "invalid JSON body: " + err.Error()})
+		return
+	}
+
+	command := strings.TrimSpace(req.Cmd)
+	if command == "" {
+		writeBridgeJSON(w, http.StatusBadRequest, bridgeError{Error: "missing cmd"})
+		return
+	}
+
+	command, err := b.client.config.prepareCommand(command)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrCommandDenied) {
+			status = http.StatusForbidden
+		}
+		writeBridgeJSON(w, status, bridgeError{Command: strings.TrimSpace(req.Cmd), Error: err.Error()})
+		return
+	}
+
+	body, err := b.client.runBridgeCommand(command)
+	if err != nil {
+		writeBridgeJSON(w, http.StatusBadGateway, bridgeError{Command: command, Error: err.Error()})
+		return
+	}
+
+	writeBridgeJSON(w, http.StatusOK, bridgeResponse{Command: command, Response: stripColorCodes(body, b.client.config.AmpersandCodes)})
+}
+
+// runBridgeCommand sends a prepared command, recording it in the metrics
+// and transcript like ExecuteCommand does without printing the response
+func (c *RCONClient) runBridgeCommand(command string) (string, error) {
+	start := time.Now()
+	body, err := c.sendReconnecting(command)
+	if c.config.Metrics != nil {
+		c.config.Metrics.observe(time.Since(start), err)
+	}
+	if c.config.Transcript != nil {
+		c.writeTranscript(command, body, err)
+	}
+	return body, err
+}
+
+// bridgeRequestError is a rejected bridge request and its HTTP status
+type bridgeRequestError struct {
+	status  int
+	message string
+}
+
+// checkBridgeRequest rejects requests a browser could send on behalf of
+// another site: bodies that are not JSON, which need no CORS preflight,
+// cross-origin requests and Host names other than the listen address,
+// which DNS rebinding would use
+func checkBridgeRequest(r *http.Request) *bridgeRequestError {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return &bridgeRequestError{http.StatusUnsupportedMediaType, "Content-Type must be application/json"}
+	}
+
+	if !bridgeHostAllowed(r) {
+		return &bridgeRequestError{http.StatusForbidden, "Host " + r.Host + " does not match the listen address"}
+	}
+
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host != r.Host {
+			return &bridgeRequestError{http.StatusForbidden, "cross-origin requests are not allowed"}
+		}
+	}
+	if r.Header.Get("Sec-Fetch-Site") == "cross-site" {
+		return &bridgeRequestError{http.StatusForbidden, "cross-origin requests are not allowed"}
+	}
+	return nil
+}
+
+// bridgeHostAllowed reports whether the request's Host names the address
+// the connection was accepted on, either literally or as localhost for a
+// loopback address
+func bridgeHostAllowed(r *http.Request) bool {
+	local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return false
+	}
+	localHost, localPort, err := net.SplitHostPort(local.String())
+	if err != nil {
+		return false
+	}
+
+	host, port, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host, port = r.Host, "80"
+	}
+	if port != localPort {
+		return false
+	}
+
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		ip := net.ParseIP(localHost)
+		return ip != nil && ip.IsLoopback()
+	}
+	hostIP, localIP := net.ParseIP(host), net.ParseIP(localHost)
+	return hostIP != nil && hostIP.Equal(localIP)
+}
+
+func writeBridgeJSON(w http.ResponseWriter, status int, resp any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,181 @@
+package mcrcon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestBridge serves a bridge for a client authenticated with a fake
+// server that answers with reply
+func newTestBridge(t *testing.T, reply func(command string) string) *httptest.Server {
+	t.Helper()
+
+	client := newAuthenticatedClient(t, &Config{}, rconHandler(reply))
+	server := httptest.NewServer(NewBridgeHandler(client))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// postCommand sends a bridge request for cmd, letting edit adjust it
+// first. It may run on its own goroutine, so it reports errors without
+// stopping the test.
+func postCommand(t *testing.T, server *httptest.Server, cmd string, edit func(r *http.Request)) (int, map[string]string) {
+	t.Helper()
+
+	body, _ := json.Marshal(bridgeRequest{Cmd: cmd})
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/command", strings.NewReader(string(body)))
+	if err != nil {
+		t.Error(err)
+		return 0, nil
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if edit != nil {
+		edit(req)
+	}
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Error(err)
+		return 0, nil
+	}
+	defer resp.Body.Close()
+
+	var fields map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&fields); err != nil {
+		t.Errorf("decoding response: %v", err)
+	}
+	return resp.StatusCode, fields
+}
+
+func TestBridgeCommand(t *testing.T) {
+	server := newTestBridge(t, func(command string) string {
+		return "§aThere are 0 of a max of 20 players online"
+	})
+
+	status, fields := postCommand(t, server, "  list  ", nil)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d (%v)", status, http.StatusOK, fields)
+	}
+	if fields["command"] != "list" {
+		t.Errorf("command = %q, want %q", fields["command"], "list")
+	}
+	if want := "There are 0 of a max of 20 players online"; fields["response"] != want {
+		t.Errorf("response = %q, want %q", fields["response"], want)
+	}
+}
+
+func TestBridgeConcurrentRequests(t *testing.T) {
+	server := newTestBridge(t, echo)
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := fmt.Sprintf("say %d", i)
+			status, fields := postCommand(t, server, cmd, nil)
+			if status != http.StatusOK || fields["response"] != cmd {
+				t.Errorf("%s: status %d, response %q", cmd, status, fields["response"])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestBridgeRejectsRequests(t *testing.T) {
+	server := newTestBridge(t, echo)
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	tests := []struct {
+		name   string
+		cmd    string
+		edit   func(r *http.Request)
+		status int
+	}{
+		{"GET", "list", func(r *http.Request) { r.Method = http.MethodGet }, http.StatusMethodNotAllowed},
+		{"missing cmd", " ", nil, http.StatusBadRequest},
+		{"form body", "list", func(r *http.Request) {
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}, http.StatusUnsupportedMediaType},
+		{"text body", "list", func(r *http.Request) { r.Header.Set("Content-Type", "text/plain") }, http.StatusUnsupportedMediaType},
+		{"other origin", "list", func(r *http.Request) { r.Header.Set("Origin", "http://evil.example") }, http.StatusForbidden},
+		{"cross-site fetch", "list", func(r *http.Request) { r.Header.Set("Sec-Fetch-Site", "cross-site") }, http.StatusForbidden},
+		{"rebound host", "list", func(r *http.Request) { r.Host = "evil.example:" + port }, http.StatusForbidden},
+		{"other port", "list", func(r *http.Request) { r.Host = "127.0.0.1:1" }, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, fields := postCommand(t, server, tt.cmd, tt.edit)
+			if status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			if fields["error"] == "" {
+				t.Errorf("response has no error: %v", fields)
+			}
+		})
+	}
+}
+
+func TestBridgeDeniedCommand(t *testing.T) {
+	commands := make(chan string, 1)
+	client := newAuthenticatedClient(t, &Config{Deny: commandPatterns(t, "stop")}, recordingHandler(commands))
+	server := httptest.NewServer(NewBridgeHandler(client))
+	defer server.Close()
+
+	status, fields := postCommand(t, server, "stop", nil)
+	if status != http.StatusForbidden {
+		t.Errorf("status = %d, want %d (%v)", status, http.StatusForbidden, fields)
+	}
+	if len(commands) != 0 {
+		t.Errorf("the server received %q", <-commands)
+	}
+}
+
+func TestBridgePreparesCommand(t *testing.T) {
+	commands := make(chan string, 1)
+	config := &Config{
+		CommandPrefix: "execute in world_nether run",
+		Vars:          map[string]string{"player": "Steve"},
+		Deny:          commandPatterns(t, "execute"),
+	}
+	client := newAuthenticatedClient(t, config, recordingHandler(commands))
+	server := httptest.NewServer(NewBridgeHandler(client))
+	defer server.Close()
+
+	// The deny pattern sees the command before the prefix is added
+	status, fields := postCommand(t, server, "tp ${player} 0 64 0", nil)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d (%v)", status, http.StatusOK, fields)
+	}
+	if got, want := <-commands, "execute in world_nether run tp Steve 0 64 0"; got != want {
+		t.Errorf("the server received %q, want %q", got, want)
+	}
+
+	status, fields = postCommand(t, server, "tp ${target} 0 64 0", nil)
+	if status != http.StatusBadRequest {
+		t.Errorf("status = %d for an unset variable, want %d (%v)", status, http.StatusBadRequest, fields)
+	}
+	if len(commands) != 0 {
+		t.Errorf("the server received %q", <-commands)
+	}
+}
+
+func TestBridgeAllowsLocalhost(t *testing.T) {
+	server := newTestBridge(t, echo)
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	status, fields := postCommand(t, server, "list", func(r *http.Request) {
+		r.Host = "localhost:" + port
+		r.Header.Set("Origin", "http://localhost:"+port)
+	})
+	if status != http.StatusOK {
+		t.Errorf("status = %d, want %d (%v)", status, http.StatusOK, fields)
+	}
+}
@@ -0,0 +1,102 @@
+package mcrcon
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// Charset converts between UTF-8 text and the bytes a server sends and
+// expects, for servers that don't use UTF-8
+type Charset interface {
+	Encode(text string) (string, error) // UTF-8 command text to server bytes
+	Decode(raw string) (string, error)  // Server bytes to UTF-8 response text
+}
+
+// NewCharset adapts an encoding from golang.org/x/text to a Charset
+func NewCharset(name string, enc encoding.Encoding) Charset {
+	return &encodingCharset{name: name, enc: enc}
+}
+
+// encodingCharset is a Charset backed by a golang.org/x/text encoding
+type encodingCharset struct {
+	name string
+	enc  encoding.Encoding
+}
+
+// Encode implements Charset
+func (e *encodingCharset) Encode(text string) (string, error) {
+	raw, err := e.enc.NewEncoder().String(text)
+	if err != nil {
+		return "", fmt.Errorf("%q can't be encoded in %s: %w", text, e.name, err)
+	}
+	return raw, nil
+}
+
+// Decode implements Charset. Invalid bytes decode to U+FFFD.
+func (e *encodingCharset) Decode(raw string) (string, error) {
+	return e.enc.NewDecoder().String(raw)
+}
+
+// multiByteCharsets are the supported multi-byte charsets by name
+var multiByteCharsets = map[string]encoding.Encoding{
+	"gbk":       simplifiedchinese.GBK,
+	"cp936":     simplifiedchinese.GBK,
+	"gb18030":   simplifiedchinese.GB18030,
+	"big5":      traditionalchinese.Big5,
+	"shift-jis": japanese.ShiftJIS,
+	"shiftjis":  japanese.ShiftJIS,
+	"sjis":      japanese.ShiftJIS,
+	"euc-jp":    japanese.EUCJP,
+	"euc-kr":    korean.EUCKR,
+}
+
+// ParseCharset returns the Charset with the given name, or nil for UTF-8,
+// which is passed through unchanged. Names are matched ignoring case and
+// treating '_' and ' ' like '-': gbk, gb18030, big5, shift-jis, euc-jp,
+// euc-kr and the single-byte charsets of golang.org/x/text/encoding/charmap
+// such as iso-8859-1 (latin1), windows-1252 (cp1252), koi8-r and cp437.
+func ParseCharset(name string) (Charset, error) {
+	key := charsetKey(name)
+	switch key {
+	case "utf-8", "utf8":
+		return nil, nil
+	case "latin1", "latin-1", "iso8859-1":
+		key = "iso-8859-1"
+	}
+
+	if enc, ok := multiByteCharsets[key]; ok {
+		return NewCharset(name, enc), nil
+	}
+	for _, enc := range charmap.All {
+		if slices.Contains(charmapNames(enc), key) {
+			return NewCharset(name, enc), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported charset %q: use utf-8, gbk, shift-jis, iso-8859-1, windows-1252 or another golang.org/x/text charset", name)
+}
+
+// charsetKey normalizes a charset name for lookup
+func charsetKey(name string) string {
+	return strings.NewReplacer("_", "-", " ", "-").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// charmapNames returns the names a charmap is matched by: its own name,
+// such as "iso-8859-1" or "windows-1252", and cpN for code pages
+func charmapNames(enc encoding.Encoding) []string {
+	name := charsetKey(fmt.Sprint(enc))
+	names := []string{name}
+	for _, prefix := range []string{"ibm-code-page-", "windows-code-page-", "windows-"} {
+		if number, ok := strings.CutPrefix(name, prefix); ok {
+			names = append(names, "cp"+number)
+		}
+	}
+	return names
+}
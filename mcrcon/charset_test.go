@@ -0,0 +1,90 @@
+package mcrcon
+
+import "testing"
+
+func TestCharsetRoundTrip(t *testing.T) {
+	tests := []struct {
+		charset string
+		text    string
+		raw     string
+	}{
+		{"gbk", "say 你好", "say \xc4\xe3\xba\xc3"},
+		{"GBK", "欢迎", "\xbb\xb6\xd3\xad"},
+		{"shift_jis", "say こんにちは", "say \x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd"},
+		{"iso-8859-1", "café", "caf\xe9"},
+		{"cp1252", "€5", "\x805"},
+		{"koi8-r", "мир", "\xcd\xc9\xd2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.charset, func(t *testing.T) {
+			charset, err := ParseCharset(tt.charset)
+			if err != nil {
+				t.Fatalf("ParseCharset(%q): %v", tt.charset, err)
+			}
+
+			raw, err := charset.Encode(tt.text)
+			if err != nil {
+				t.Fatalf("Encode(%q): %v", tt.text, err)
+			}
+			if raw != tt.raw {
+				t.Errorf("Encode(%q) = %q, want %q", tt.text, raw, tt.raw)
+			}
+
+			text, err := charset.Decode(raw)
+			if err != nil {
+				t.Fatalf("Decode(%q): %v", raw, err)
+			}
+			if text != tt.text {
+				t.Errorf("Decode(%q) = %q, want %q", raw, text, tt.text)
+			}
+		})
+	}
+}
+
+func TestParseCharset(t *testing.T) {
+	for _, name := range []string{"utf-8", "UTF8"} {
+		if charset, err := ParseCharset(name); charset != nil || err != nil {
+			t.Errorf("ParseCharset(%q) = %v, %v, want nil, nil", name, charset, err)
+		}
+	}
+
+	if _, err := ParseCharset("ebcdic-klingon"); err == nil {
+		t.Error("ParseCharset accepted an unknown charset")
+	}
+}
+
+func TestCharsetUnencodable(t *testing.T) {
+	charset, err := ParseCharset("iso-8859-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := charset.Encode("say 你好"); err == nil {
+		t.Error("Encode succeeded for text ISO-8859-1 can't represent")
+	}
+}
+
+func TestSendCharset(t *testing.T) {
+	charset, err := ParseCharset("gbk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan string, 1)
+	handle := rconHandler(func(command string) string {
+		received <- command
+		return "\xbb\xb6\xd3\xad" // 欢迎
+	})
+	client := newAuthenticatedClient(t, &Config{Charset: charset}, handle)
+
+	body, err := client.Send("say 你好")
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got, want := <-received, "say \xc4\xe3\xba\xc3"; got != want {
+		t.Errorf("server received %q, want %q", got, want)
+	}
+	if body != "欢迎" {
+		t.Errorf("Send = %q, want %q", body, "欢迎")
+	}
+}
@@ -1,69 +1,259 @@
 package mcrcon
 
 import (
-	"encoding/binary"
+	"bufio"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
-    "github.com/chzyer/readline"
+	"github.com/chzyer/readline"
 )
 
-// RCONClient manages the RCON connection
+// RCONClient manages the RCON connection. It is safe for concurrent use:
+// each request and its response are exchanged while holding a lock, so
+// responses are never mixed up between goroutines.
 type RCONClient struct {
-	conn   net.Conn
+	mu     sync.Mutex // guards conn, nextID, authenticated and readBuf during request/response exchanges
+	connMu sync.Mutex // also guards conn and isClosed, so Close can run during an exchange or while Connect dials
+	conn   net.Conn   // replaced only while holding both mu and connMu
 	config *Config
+	nextID int32 // ID of the next request packet
+
+	authenticated bool        // whether the current connection has authenticated
+	connected     atomic.Bool // false once the connection is closed or found dropped
+
+	readBuf []byte // holds the packet being received, guarded by mu
+
+	lastSend atomic.Int64 // Unix nanoseconds of the last command sent, for heartbeats
+
+	closed    chan struct{} // closed by Close to interrupt waits between commands
+	closeOnce sync.Once
+	isClosed  bool // set by Close; later connections are closed at once
+
+	queueMu sync.Mutex
+	queue   []string // commands waiting for the next authentication, see QueueCommand
 }
 
 // NewRCONClient creates a new RCON client connection
 func NewRCONClient(config *Config) (*RCONClient, error) {
-	address := net.JoinHostPort(config.Host, config.Port)
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Nothing is sent in dry-run mode, so don't connect at all
+	if config.DryRun {
+		return &RCONClient{config: config, nextID: config.requestID(), closed: make(chan struct{})}, nil
+	}
+
+	conn, err := dial(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClientWithConn(conn, config), nil
+}
+
+// NewClientWithConn creates a client that uses an existing connection,
+// such as one end of a net.Pipe, instead of dialing. The config is not
+// validated; Connect and reconnects still dial through config.
+func NewClientWithConn(conn net.Conn, config *Config) *RCONClient {
+	c := &RCONClient{
+		conn:   conn,
+		config: config,
+		nextID: config.requestID(),
+		closed: make(chan struct{}),
+	}
+	c.connected.Store(conn != nil)
+	return c
+}
+
+// dial connects to the configured server, retrying failed attempts
+func dial(config *Config) (net.Conn, error) {
+	address := config.address()
+	if config.SRV && config.Port == "" && config.unixSocket() == "" {
+		address = config.resolveSRV(address)
+	}
+
+	dialer, err := config.dialer()
+	if err != nil {
+		return nil, err
+	}
 
 	// Add retry logic for connection
 	var conn net.Conn
-	var err error
 
-	for i := range 3 {
-		conn, err = net.DialTimeout("tcp", address, 10*time.Second)
+	attempts := dialAttempts
+	if config.NoRetry {
+		attempts = 1
+	}
+
+	log := config.logger()
+	for i := range attempts {
+		log.Debug("dialing", "address", address, "attempt", i+1, "proxy", config.Proxy)
+		conn, err = dialer.Dial(config.network(), address)
+		if err == nil && config.TLS {
+			conn, err = config.handshakeTLS(conn)
+		}
 		if err == nil {
 			break
 		}
-		if i < 2 {
-			time.Sleep(time.Second)
+		log.Debug("dial failed", "address", address, "attempt", i+1, "error", err)
+
+		// Retrying won't make an unknown host resolve, and each attempt
+		// can take as long as the DNS timeout
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, fmt.Errorf("could not resolve host %s: %w", dnsErr.Name, dnsErr)
+		}
+
+		if i < attempts-1 {
+			config.clock().Sleep(time.Second)
 		}
 	}
 
 	if err != nil {
+		if config.SourceAddr != "" && errors.Is(err, syscall.EADDRNOTAVAIL) {
+			return nil, fmt.Errorf("failed to connect to %s: cannot bind source address %s: %w", address, config.SourceAddr, err)
+		}
 		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
 	}
+	log.Debug("connected", "local", conn.LocalAddr(), "remote", conn.RemoteAddr())
 
-	// Disable Nagle's algorithm for better performance
-	if tcpConn, ok := conn.(*net.TCPConn); ok {
+	raw := conn
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		raw = tlsConn.NetConn()
+	}
+
+	if tcpConn, ok := raw.(*net.TCPConn); ok {
+		// Disable Nagle's algorithm for better performance
 		tcpConn.SetNoDelay(true)
+
+		// Keep idle connections from being dropped by NAT and firewalls
+		if period := config.keepAlive(); period > 0 {
+			tcpConn.SetKeepAlive(true)
+			tcpConn.SetKeepAlivePeriod(period)
+		} else {
+			tcpConn.SetKeepAlive(false)
+		}
 	}
 
-	return &RCONClient{
-		conn:   conn,
-		config: config,
-	}, nil
+	return conn, nil
+}
+
+// Connect dials the server, replacing the current connection if there is
+// one. The new connection must be authenticated before sending commands.
+func (c *RCONClient) Connect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.connMu.Lock()
+	if c.conn != nil {
+		c.conn.Close()
+		c.connected.Store(false)
+	}
+	c.connMu.Unlock()
+
+	conn, err := dial(c.config)
+	if err != nil {
+		return err
+	}
+
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
+	// Close may have run while dialing; don't leak the new connection
+	if c.isClosed {
+		conn.Close()
+		return fmt.Errorf("%w: client was closed while connecting", ErrConnClosed)
+	}
+	c.conn = conn
+	c.authenticated = false
+	c.connected.Store(true)
+
+	return nil
 }
 
-// Close closes the RCON connection
+// Reauthenticate re-dials the server and authenticates the new
+// connection, restoring a session after the connection was lost
+func (c *RCONClient) Reauthenticate() error {
+	c.config.logger().Debug("reconnecting")
+	if err := c.Connect(); err != nil {
+		return err
+	}
+	return c.Authenticate()
+}
+
+// Close closes the RCON connection and interrupts a RunCommands wait. The
+// client can't connect again afterwards.
 func (c *RCONClient) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	c.connected.Store(false)
+
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
+	c.isClosed = true
 	if c.conn != nil {
 		return c.conn.Close()
 	}
 	return nil
 }
 
-// Authenticate performs RCON authentication
+// RemoteAddr returns the address of the connected server, which may
+// differ from the configured host after SRV lookup or name resolution.
+// It returns nil when not connected, as in dry-run mode. With a proxy it
+// is the proxy's address.
+func (c *RCONClient) RemoteAddr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.RemoteAddr()
+}
+
+// IsConnected reports whether the client has a connection that hasn't
+// been closed or found dropped. A connection the server closed is only
+// noticed by the next attempt to use it, so a true result doesn't
+// guarantee the next command succeeds; callers can reconnect with
+// Reauthenticate when it is false.
+func (c *RCONClient) IsConnected() bool {
+	return c.connected.Load()
+}
+
+// Authenticate performs RCON authentication, then sends any commands
+// queued with QueueCommand
 func (c *RCONClient) Authenticate() error {
+	if err := c.authenticate(); err != nil {
+		return err
+	}
+	c.flushQueue()
+	return nil
+}
+
+// authenticate implements Authenticate
+func (c *RCONClient) authenticate() error {
+	if c.config.DryRun {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	packet := &RCONPacket{
-		ID:   rconPID,
+		ID:   c.requestID(),
 		Type: rconAuthenticate,
 		Body: c.config.Password,
 	}
@@ -77,66 +267,342 @@ func (c *RCONClient) Authenticate() error {
 		return fmt.Errorf("failed to receive auth response: %w", err)
 	}
 
+	// Source engine servers send an empty response value packet before
+	// the actual auth response, so skip it. One sent after the auth
+	// response is left over and discarded by Send as a stale response.
+	if response.Type == rconResponseValue && len(response.Body) == 0 {
+		response, err = c.receivePacket()
+		if err != nil {
+			return fmt.Errorf("failed to receive auth response: %w", err)
+		}
+	}
+
 	if response.ID == -1 {
-		return errors.New("authentication rejected")
+		c.authenticated = false
+		return ErrAuthFailed
+	}
+
+	if response.Type != rconAuthResponse {
+		return unexpectedTypeError(rconAuthResponse, response)
 	}
+	c.authenticated = true
 
+	c.config.logger().Debug("authenticated", "remote", c.conn.RemoteAddr())
 	return nil
 }
 
-// ExecuteCommand sends a command and prints the response
+// ExecuteCommand sends a command, with the configured CommandPrefix and
+// variables expanded, and prints the response
 func (c *RCONClient) ExecuteCommand(command string) error {
-	// Validate command length
-	if len(command) >= dataBuffSize {
-		return fmt.Errorf("command too long (%d bytes). Maximum: %d", len(command), dataBuffSize-1)
+	command, err := c.config.prepareCommand(command)
+	if err != nil {
+		return err
+	}
+
+	if c.config.DryRun {
+		fmt.Println(command)
+		return nil
+	}
+
+	if c.config.Metrics == nil {
+		return c.executeCommand(command)
+	}
+
+	start := time.Now()
+	err = c.executeCommand(command)
+	c.config.Metrics.observe(time.Since(start), err)
+	return err
+}
+
+// executeCommand implements ExecuteCommand
+func (c *RCONClient) executeCommand(command string) error {
+	start := time.Now()
+	body, err := c.sendReconnecting(command)
+	return c.handleResponse(command, body, time.Since(start), err)
+}
+
+// handleResponse records, prints and checks the outcome of a command as
+// configured, returning the command's error
+func (c *RCONClient) handleResponse(command, body string, latency time.Duration, err error) error {
+	if c.config.EchoCommand && c.config.Format == nil && c.config.ResponseHandler == nil && !c.config.SilentMode {
+		fmt.Printf("$ %s\n", command)
+	}
+
+	if c.config.Transcript != nil {
+		c.writeTranscript(command, body, err)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if c.config.ResponseHandler != nil {
+		c.config.ResponseHandler(command, body)
+	} else if c.config.Format != nil && !c.config.SilentMode {
+		c.printFormatted(command, body, latency)
+	} else if !c.config.SilentMode {
+		if len(body) > 0 {
+			c.printResponse(body)
+		} else if c.config.EmptyMarker != "" {
+			fmt.Println(c.config.EmptyMarker)
+		}
+	}
+
+	if c.config.Timing {
+		fmt.Fprintf(os.Stderr, "[%.2fms]\n", float64(latency.Microseconds())/1000)
+	}
+
+	// Treat responses matching the failure pattern as failed commands
+	if c.config.FailOn != nil {
+		text := stripColorCodes(body, c.config.AmpersandCodes)
+		if c.config.FailOn.MatchString(text) {
+			return fmt.Errorf("response to %q matched failure pattern: %s", command, strings.TrimSpace(text))
+		}
+	}
+
+	return nil
+}
+
+// sendReconnecting sends a command like Send. With AutoReconnect set, a
+// command that hits a closed connection is retried once after re-dialing,
+// and one the server refuses with ErrReauthRequired is retried once after
+// authenticating again. With TimeoutRetries set, a command whose response
+// times out is sent again up to that many times.
+func (c *RCONClient) sendReconnecting(command string) (string, error) {
+	body, err := c.Send(command)
+	if c.config.AutoReconnect && errors.Is(err, ErrReauthRequired) {
+		fmt.Fprintln(os.Stderr, "Session expired, authenticating again...")
+		if aerr := c.authenticate(); aerr != nil {
+			return "", fmt.Errorf("%w (reauthentication failed: %v)", err, aerr)
+		}
+		body, err = c.Send(command)
+	}
+	for retry := 1; retry <= c.config.TimeoutRetries && isTimeout(err); retry++ {
+		fmt.Fprintf(os.Stderr, "Response timed out, retrying (%d/%d)...\n", retry, c.config.TimeoutRetries)
+		body, err = c.Send(command)
+	}
+	if err != nil && c.config.AutoReconnect && errors.Is(err, ErrConnClosed) && !errors.Is(err, ErrClosedAfterSend) {
+		// A command the server closed the connection after, such as stop,
+		// was delivered and isn't sent again
+		fmt.Fprintln(os.Stderr, "Connection lost, reconnecting...")
+		if rerr := c.Reauthenticate(); rerr != nil {
+			return "", fmt.Errorf("%w (reconnect failed: %v)", err, rerr)
+		}
+		body, err = c.Send(command)
+	}
+	return body, err
+}
+
+// Send sends a command and returns the response body without printing it
+func (c *RCONClient) Send(command string) (string, error) {
+	if c.config.DryRun {
+		return "", nil
+	}
+
+	response, err := c.SendPacket(command)
+	if err != nil {
+		return "", err
+	}
+	return truncateBody(response.Body, c.config.MaxBodyBytes), nil
+}
+
+// SendPacket sends a command like Send but returns the whole response
+// packet, for servers whose Type field carries meaning. The body is not
+// shortened to MaxBodyBytes. In dry-run mode the response is empty.
+func (c *RCONClient) SendPacket(command string) (*RCONPacket, error) {
+	if c.config.DryRun {
+		return &RCONPacket{Type: rconResponseValue}, nil
+	}
+
+	body, err := c.config.encodeCommand(command)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.config.Drain {
+		c.drain()
 	}
 
 	packet := &RCONPacket{
-		ID:   rconPID,
+		ID:   c.requestID(),
 		Type: rconExecCommand,
-		Body: command,
+		Body: body,
 	}
 
 	if err := c.sendPacket(packet); err != nil {
-		return fmt.Errorf("failed to send command: %w", err)
+		return nil, fmt.Errorf("failed to send command: %w", err)
 	}
+	c.lastSend.Store(time.Now().UnixNano())
 
 	response, err := c.receivePacket()
 	if err != nil {
-		return fmt.Errorf("failed to receive response: %w", err)
+		if errors.Is(err, ErrConnClosed) {
+			return nil, fmt.Errorf("%w: %w", ErrClosedAfterSend, err)
+		}
+		return nil, fmt.Errorf("failed to receive response: %w", err)
 	}
 
-	if response.ID != rconPID {
-		return errors.New("invalid response ID")
+	// Late responses to earlier requests are discarded in favor of the
+	// response to this one. They follow timeouts, and servers that send
+	// the auth response and an empty value packet in the opposite order
+	// to the one Authenticate expects leave one behind for the first
+	// command.
+	for c.isEarlierID(response.ID, packet.ID) {
+		c.config.logger().Debug("discarding stale response", "id", response.ID, "expected", packet.ID)
+		response, err = c.receivePacket()
+		if err != nil {
+			return nil, fmt.Errorf("failed to receive response: %w", err)
+		}
 	}
 
-	if !c.config.SilentMode && len(response.Body) > 0 {
-		c.printResponse(response.Body)
+	if response.ID == -1 {
+		c.authenticated = false
+		return nil, ErrReauthRequired
 	}
 
-	return nil
+	if response.ID != packet.ID {
+		return nil, unexpectedIDError(packet.ID, response)
+	}
+
+	return response, nil
+}
+
+// SendRaw sends a packet with any type and body and returns the next
+// packet received, without checking its ID or type, for protocol
+// debugging. With RawHex set, the response is dumped as received. In
+// dry-run mode the packet is dumped to standard output instead of being
+// sent, and the returned packet is nil.
+func (c *RCONClient) SendRaw(packetType int32, body string) (*RCONPacket, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	packet := &RCONPacket{
+		ID:   c.requestID(),
+		Type: packetType,
+		Body: body,
+	}
+
+	if c.config.DryRun {
+		buf := packet.encode(c.config.byteOrder())
+		dumpPacket(os.Stdout, packet, buf)
+		return nil, nil
+	}
+
+	if err := c.sendPacket(packet); err != nil {
+		return nil, fmt.Errorf("failed to send packet: %w", err)
+	}
+
+	response, err := c.receivePacket()
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive response: %w", err)
+	}
+	return response, nil
+}
+
+// truncateBody shortens body to at most max bytes, not counting the
+// marker it appends, without splitting a UTF-8 character. A max of 0 or
+// less means no limit.
+func truncateBody(body string, max int) string {
+	if max <= 0 || len(body) <= max {
+		return body
+	}
+
+	cut := max
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return body[:cut] + truncatedMarker
+}
+
+// isEarlierID reports whether id belongs to a request sent before the one with ID current
+func (c *RCONClient) isEarlierID(id, current int32) bool {
+	return id >= c.config.requestID() && id < current
+}
+
+// unexpectedIDError describes a response that doesn't match the request,
+// including the server's response text which often explains why
+func unexpectedIDError(expected int32, response *RCONPacket) error {
+	body := strings.TrimSpace(stripColorCodes(response.Body, false))
+	if body == "" {
+		return fmt.Errorf("unexpected response ID %d (expected %d)", response.ID, expected)
+	}
+	return fmt.Errorf("unexpected response ID %d (expected %d): %s", response.ID, expected, body)
+}
+
+// unexpectedTypeError describes a response packet of the wrong type,
+// including its text in case the server explains itself there
+func unexpectedTypeError(expected int32, response *RCONPacket) error {
+	body := strings.TrimSpace(stripColorCodes(response.Body, false))
+	if body == "" {
+		return fmt.Errorf("unexpected response type %d (expected %d)", response.Type, expected)
+	}
+	return fmt.Errorf("unexpected response type %d (expected %d): %s", response.Type, expected, body)
+}
+
+// Ping sends an empty command and returns the round-trip time
+func (c *RCONClient) Ping() (time.Duration, error) {
+	start := time.Now()
+	if _, err := c.Send(""); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
 }
 
-// RunTerminalMode runs interactive terminal mode
+// RunTerminalMode runs interactive terminal mode. With standard input
+// redirected from a file, it reads the commands without printing the
+// banner or prompts.
 func (c *RCONClient) RunTerminalMode() int {
-	fmt.Println("Logged in.")
-	fmt.Println("Type 'Q' or press Ctrl-D / Ctrl-C to disconnect.")
+	// Commands redirected from a file run as a clean batch, without the
+	// banner and prompts meant for people and tools driving the session
+	batch := stdinIsFile()
+
+	if !c.config.QuietAuth && !batch {
+		fmt.Fprintln(os.Stderr, "Logged in.")
+		fmt.Fprintln(os.Stderr, "Type 'Q' or press Ctrl-D / Ctrl-C to disconnect.")
+	}
+
+	prompt, eofPrompt := "> ", "exit"
+	if c.config.NoPrompt || batch {
+		prompt, eofPrompt = "", ""
+	}
 
-	// Configure readline with history
+	// Configure readline with history. History is saved manually so that
+	// blank lines and consecutive duplicates are left out.
 	rl, err := readline.NewEx(&readline.Config{
-		Prompt:          "> ",
-		HistoryFile:     os.ExpandEnv("$HOME/.mcrcon_history"),
-		AutoComplete:    newCommandCompleter(),
-		InterruptPrompt: "^C",
-		EOFPrompt:       "exit",
+		Prompt:                 prompt,
+		HistoryFile:            historyFile(),
+		DisableAutoSaveHistory: true,
+		AutoComplete:           newCommandCompleter(),
+		InterruptPrompt:        "^C",
+		EOFPrompt:              eofPrompt,
+		Stdout:                 os.Stderr, // keep stdout for responses
 	})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to initialize readline: %w\n", err)
+		fmt.Fprintf(os.Stderr, "Failed to initialize readline: %v\n", err)
 		return -1
 	}
 	defer rl.Close()
 
+	if interval := c.config.HeartbeatInterval; interval > 0 {
+		stop := make(chan struct{})
+		defer close(stop)
+		go c.heartbeat(interval, stop)
+	}
+
+	// readline only draws the prompt on terminals, so write it directly
+	// when driven through pipes, e.g. by expect-style tools waiting for it.
+	// os.Stderr is unbuffered, so the prompt is out before the read blocks.
+	interactive := rl.Config.FuncIsTerminal()
+
+	var lastCommand string
 	for {
+		if !interactive && prompt != "" {
+			io.WriteString(os.Stderr, prompt)
+		}
+
 		line, err := rl.Readline()
 		if err != nil { // io.EOF or readline.ErrInterrupt
 			break
@@ -151,12 +617,23 @@ func (c *RCONClient) RunTerminalMode() int {
 			break
 		}
 
-		if err := c.ExecuteCommand(command); err != nil {
+		if command != lastCommand {
+			rl.SaveHistory(command)
+			lastCommand = command
+		}
+
+		err = c.ExecuteCommand(command)
+		if errors.Is(err, ErrClosedAfterSend) {
+			fmt.Fprintln(os.Stderr, "Server closed the connection.")
+			break
+		} else if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		} else if c.config.Follow {
+			c.followOutput()
 		}
 
-		// Exit on "stop" command to avoid server-side bug
-		if strings.EqualFold(command, "stop") {
+		// Exit on the stop command to avoid server-side bug
+		if !c.config.NoStopBreak && strings.EqualFold(command, c.config.stopCommand()) {
 			break
 		}
 	}
@@ -164,125 +641,470 @@ func (c *RCONClient) RunTerminalMode() int {
 	return 0
 }
 
-// RunCommands executes multiple commands with optional delays
-func (c *RCONClient) RunCommands(args []string) int {
-	commands := extractCommands(args)
+// heartbeat sends an empty command whenever no command was sent for the
+// given interval, until stop is closed, so that idle connections aren't
+// dropped. Send holds the client's lock, so heartbeat responses can't be
+// mistaken for responses to the user's commands.
+func (c *RCONClient) heartbeat(interval time.Duration, stop <-chan struct{}) {
+	c.lastSend.Store(time.Now().UnixNano())
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		if time.Since(time.Unix(0, c.lastSend.Load())) < interval {
+			continue
+		}
+		c.config.logger().Debug("sending heartbeat")
+		if _, err := c.Send(""); err != nil {
+			c.config.logger().Debug("heartbeat failed", "error", err)
+		}
+	}
+}
+
+// historyFile returns the path of the terminal mode history file, or an
+// empty string to keep history in memory only
+func historyFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".mcrcon_history")
+}
+
+// RunCommands executes multiple commands with optional delays.
+// A failed command aborts the batch with status 1 unless KeepGoing is
+// set, in which case the remaining commands still run and the status is
+// 10 plus the number of failed commands, capped at 125, which keeps it
+// apart from the statuses for other errors. Batches larger than
+// MaxCommands are refused before anything runs.
+func (c *RCONClient) RunCommands(commands []string) int {
+	if err := c.config.checkCommandCount(len(commands)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	i := 0
+	return c.run(func() (string, bool) {
+		if i == len(commands) {
+			return "", false
+		}
+		i++
+		return commands[i-1], true
+	}, false)
+}
+
+// RunRepeated executes commands count times over, or until the client is
+// closed if count is 0, and prints a summary of successes and failures.
+// Delays and failures are handled as in RunCommands.
+func (c *RCONClient) RunRepeated(commands []string, count int) int {
 	if len(commands) == 0 {
 		return 0
 	}
+	if err := c.config.checkCommandCount(len(commands)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
 
-	for i, cmd := range commands {
-		if err := c.ExecuteCommand(cmd); err != nil {
-			fmt.Fprintf(os.Stderr, "Command failed: %v\n", err)
-			return 1
+	i := 0
+	return c.run(func() (string, bool) {
+		if count > 0 && i == count*len(commands) {
+			return "", false
+		}
+		i++
+		return commands[(i-1)%len(commands)], true
+	}, true)
+}
+
+// RunReader executes commands read line by line from r, such as a pipe,
+// as they arrive, until EOF. Blank lines and lines starting with '#' are
+// skipped. Failures and delays are handled as in RunCommands.
+func (c *RCONClient) RunReader(r io.Reader) int {
+	scanner := bufio.NewScanner(r)
+	code := c.run(func() (string, bool) {
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if len(line) > 0 && !strings.HasPrefix(line, "#") {
+				return line, true
+			}
+		}
+		return "", false
+	}, false)
+
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read commands: %v\n", err)
+		return 1
+	}
+	return code
+}
+
+// waitForReconnect re-dials and authenticates until it succeeds, backing
+// off between attempts, or until timeout passes. A rejected password
+// ends it immediately.
+func (c *RCONClient) waitForReconnect(timeout time.Duration) error {
+	clock := c.config.clock()
+	deadline := clock.Now().Add(timeout)
+	backoff := time.Second
+
+	for {
+		err := c.Reauthenticate()
+		if err == nil || errors.Is(err, ErrAuthFailed) {
+			return err
+		}
+		if clock.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("server not available within %s: %w", timeout, err)
+		}
+
+		select {
+		case <-clock.After(backoff):
+		case <-c.closed:
+			return errors.New("interrupted")
+		}
+		backoff = min(backoff*2, maxReconnectBackoff)
+	}
+}
+
+// run executes the commands returned by next until it reports no more or
+// the client is closed. With summarize set, it ends by printing how many
+// commands succeeded and failed; otherwise only failures are summarized.
+func (c *RCONClient) run(next func() (string, bool), summarize bool) int {
+	failed, count := 0, 0
+	aborted := false
+
+	// Commands like stop make the server close the connection without
+	// responding, which is only a failure if more commands follow and
+	// the server doesn't come back within ReconnectWait
+	var closedErr error
+
+loop:
+	for {
+		cmd, ok := next()
+		if !ok {
+			break
+		}
+
+		select {
+		case <-c.closed:
+			aborted = true
+			break loop
+		default:
+		}
+
+		if closedErr != nil && c.config.ReconnectWait > 0 {
+			fmt.Fprintln(os.Stderr, "Connection closed, waiting for the server...")
+			if err := c.waitForReconnect(c.config.ReconnectWait); err != nil {
+				closedErr = fmt.Errorf("%w (reconnect failed: %v)", closedErr, err)
+			} else {
+				closedErr = nil
+			}
+		}
+
+		if closedErr != nil {
+			fmt.Fprintf(os.Stderr, "Command failed: %v\n", closedErr)
+			failed++
+			closedErr = nil
+			if !c.config.KeepGoing {
+				aborted = true
+				break
+			}
 		}
 
 		// Wait between commands if configured
-		if i < len(commands)-1 && c.config.WaitSeconds > 0 {
-			time.Sleep(time.Duration(c.config.WaitSeconds) * time.Second)
+		if count > 0 && (c.config.Wait > 0 || c.config.Jitter > 0) {
+			wait := c.config.wait()
+			if c.config.DryRun {
+				fmt.Printf("(wait %s)\n", wait)
+			} else {
+				select {
+				case <-c.config.clock().After(wait):
+				case <-c.closed:
+					fmt.Fprintln(os.Stderr, "Interrupted while waiting")
+					aborted = true
+					break loop
+				}
+			}
+		}
+		count++
+
+		err := c.ExecuteCommand(cmd)
+		if errors.Is(err, ErrClosedAfterSend) {
+			closedErr = err
+			continue
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Command failed: %v\n", err)
+			failed++
+			if !c.config.KeepGoing {
+				aborted = true
+				break
+			}
 		}
 	}
 
-	return 0
+	if summarize {
+		fmt.Fprintf(os.Stderr, "%d commands sent: %d succeeded, %d failed\n", count, count-failed, failed)
+	} else if failed > 0 && !aborted {
+		fmt.Fprintf(os.Stderr, "%d of %d commands failed\n", failed, count)
+	}
+
+	return exitStatus(failed, aborted, c.config.KeepGoing)
+}
+
+// exitStatus maps the outcome of a batch to its exit status: 0 if every
+// command succeeded, failureStatusBase plus the number of failures (at
+// most maxFailureStatus) when keepGoing ran the whole batch, and 1
+// otherwise
+func exitStatus(failed int, aborted, keepGoing bool) int {
+	switch {
+	case failed == 0 && !aborted:
+		return 0
+	case keepGoing && !aborted:
+		return min(failureStatusBase+failed, maxFailureStatus)
+	default:
+		return 1
+	}
+}
+
+// requestID returns the ID for the next request packet and advances it,
+// wrapping back to the base ID rather than overflowing into negative IDs
+func (c *RCONClient) requestID() int32 {
+	id := c.nextID
+	if c.nextID == math.MaxInt32 {
+		c.nextID = c.config.requestID()
+	} else {
+		c.nextID++
+	}
+	return id
 }
 
 // sendPacket sends an RCON packet
 func (c *RCONClient) sendPacket(packet *RCONPacket) error {
-	bodyLen := len(packet.Body)
-	// Size = ID (4) + Type (4) + Body (n) + null terminator (1) + padding (1)
-	packet.Size = int32(4 + 4 + bodyLen + 2)
-
 	// Build packet in buffer to ensure atomic write
-	buf := make([]byte, 4+packet.Size)
-	binary.LittleEndian.PutUint32(buf[0:4], uint32(packet.Size))
-	binary.LittleEndian.PutUint32(buf[4:8], uint32(packet.ID))
-	binary.LittleEndian.PutUint32(buf[8:12], uint32(packet.Type))
-	copy(buf[12:], []byte(packet.Body))
-	// Null terminators already zero in buffer
-
-	// Send entire packet at once
-	_, err := c.conn.Write(buf)
+	buf := packet.encode(c.config.byteOrder())
+
+	c.config.logger().Debug("sending packet", "id", packet.ID, "type", packet.Type, "size", packet.Size)
+
+	if c.config.HexdumpSent {
+		dumpPacket(os.Stderr, packet, buf)
+	}
+
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+
+	err := wrapConnError(writeFull(c.conn, buf))
+	if errors.Is(err, ErrConnClosed) {
+		c.connected.Store(false)
+	}
 	return err
 }
 
+// writeFull writes all of buf to w, continuing after short writes from
+// connections that don't guarantee complete writes
+func writeFull(w io.Writer, buf []byte) error {
+	for len(buf) > 0 {
+		n, err := w.Write(buf)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return io.ErrShortWrite
+		}
+		buf = buf[n:]
+	}
+	return nil
+}
+
+// followOutput prints packets that arrive within the follow window after
+// a command, such as asynchronous output pushed by server plugins. In
+// dry-run mode nothing was sent, so there is nothing to follow.
+func (c *RCONClient) followOutput() {
+	if c.config.DryRun {
+		return
+	}
+
+	for {
+		c.mu.Lock()
+		response, err := c.receivePacketWithin(c.config.followWindow())
+		c.mu.Unlock()
+		if err != nil {
+			if !isTimeout(err) {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			return
+		}
+
+		if !c.config.SilentMode && len(response.Body) > 0 {
+			c.printResponse(response.Body)
+		}
+	}
+}
+
+// readBuffer returns the buffer packets are read into. It's reused across
+// calls, which is safe because receiving always happens under c.mu.
+func (c *RCONClient) readBuffer() []byte {
+	if c.readBuf == nil {
+		c.readBuf = make([]byte, 4+maxResponseSize)
+	}
+	return c.readBuf
+}
+
+// drain discards whatever the server has already sent, such as the rest
+// of a response that timed out, so that it isn't mistaken for the
+// response to the next command
+func (c *RCONClient) drain() {
+	// Nothing can have arrived before Connect
+	if c.conn == nil {
+		return
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(drainWindow))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	buf := c.readBuffer()
+	discarded := 0
+	for {
+		n, err := c.conn.Read(buf)
+		discarded += n
+		if err != nil {
+			break
+		}
+	}
+
+	if discarded > 0 {
+		c.config.logger().Debug("discarded stray bytes", "bytes", discarded)
+	}
+}
+
 // receivePacket receives an RCON packet
 func (c *RCONClient) receivePacket() (*RCONPacket, error) {
+	return c.receivePacketWithin(c.config.timeout())
+}
+
+// receivePacketWithin receives an RCON packet, failing if it doesn't
+// arrive within the given timeout
+func (c *RCONClient) receivePacketWithin(timeout time.Duration) (*RCONPacket, error) {
+	if c.conn == nil {
+		return nil, ErrNotConnected
+	}
+
 	// Set read timeout
-	c.conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
 	defer c.conn.SetReadDeadline(time.Time{})
 
-	// Read size
-	var size int32
-	if err := binary.Read(c.conn, binary.LittleEndian, &size); err != nil {
-		return nil, fmt.Errorf("failed to read packet size: %w", err)
+	packet, raw, err := decodePacket(c.conn, c.config.byteOrder(), c.readBuffer())
+	if err != nil {
+		if errors.Is(err, ErrConnClosed) {
+			c.connected.Store(false)
+		}
+		if isTimeout(err) {
+			c.config.logger().Debug("read timed out", "timeout", timeout)
+		}
+		return nil, err
 	}
 
-	// Validate size
-	if size < 10 || size > dataBuffSize {
-		return nil, fmt.Errorf("invalid packet size: %d (must be 10-%d)", size, dataBuffSize)
+	c.config.logger().Debug("received packet", "id", packet.ID, "type", packet.Type, "size", packet.Size)
+
+	if c.config.RawHex && !c.config.SilentMode {
+		dumpPacket(os.Stdout, packet, raw)
 	}
 
-	// Read the rest of the packet
-	payload := make([]byte, size)
-	if _, err := io.ReadFull(c.conn, payload); err != nil {
-		return nil, fmt.Errorf("failed to read packet payload: %w", err)
+	if c.config.Charset != nil {
+		if packet.Body, err = c.config.Charset.Decode(packet.Body); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
 	}
 
-	// Parse payload
-	id := int32(binary.LittleEndian.Uint32(payload[0:4]))
-	ptype := int32(binary.LittleEndian.Uint32(payload[4:8]))
+	return packet, nil
+}
 
-	// Body is from byte 8 to size-2 (excluding two null terminators)
-	bodySize := size - 10
-	bodyStr := string(payload[8 : 8+bodySize])
+// writeTranscript appends a timestamped entry with the command and its
+// color-stripped response (or error) to the configured transcript
+func (c *RCONClient) writeTranscript(command, body string, err error) {
+	var entry strings.Builder
+	fmt.Fprintf(&entry, "[%s] > %s\n", time.Now().Format(time.RFC3339), command)
 
-	return &RCONPacket{
-		Size: size,
-		ID:   id,
-		Type: ptype,
-		Body: bodyStr,
-	}, nil
+	if err != nil {
+		fmt.Fprintf(&entry, "error: %v\n", err)
+	} else if text := c.transcriptText(body); len(text) > 0 {
+		entry.WriteString(text)
+		if !strings.HasSuffix(text, "\n") {
+			entry.WriteByte('\n')
+		}
+	}
+
+	if _, werr := io.WriteString(c.config.Transcript, entry.String()); werr != nil {
+		c.config.logger().Debug("failed to write transcript", "error", werr)
+	}
 }
 
-func extractCommands(args []string) []string {
-	var commands []string
-	skipNext := false
+// transcriptText renders a response for the transcript, as tags with
+// ColorTags and without color codes otherwise
+func (c *RCONClient) transcriptText(body string) string {
+	if c.config.ColorTags {
+		return tagColorCodes(body, c.config.AmpersandCodes)
+	}
+	return stripColorCodes(body, c.config.AmpersandCodes)
+}
 
-	for i, arg := range args {
-		if skipNext {
-			skipNext = false
-			continue
-		}
+// stdoutIsTerminal reports whether standard output is a terminal rather
+// than a file or pipe
+var stdoutIsTerminal = sync.OnceValue(func() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+})
 
-		if strings.HasPrefix(arg, "-") {
-			// Skip flags that take arguments
-			if arg == "-H" || arg == "-P" || arg == "-p" || arg == "-w" {
-				skipNext = true
-			}
-			continue
-		}
+// stdinIsFile reports whether standard input is redirected from a
+// regular file, as opposed to a terminal or pipe
+func stdinIsFile() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode().IsRegular()
+}
 
-		if i > 0 { // Skip program name
-			commands = append(commands, arg)
-		}
+// renderText applies the color and one-line settings to a response
+func (c *RCONClient) renderText(text string) string {
+	// Drop null padding and carriage returns that render as garbage
+	text = strings.ReplaceAll(strings.TrimRight(text, "\x00"), "\r\n", "\n")
+
+	// Keep colors readable in logs and pipes, strip them if colors are
+	// disabled and convert them to ANSI otherwise
+	switch {
+	case c.config.ColorTags && !stdoutIsTerminal():
+		text = tagColorCodes(text, c.config.AmpersandCodes)
+	case c.config.DisableColors:
+		text = stripColorCodes(text, c.config.AmpersandCodes)
+	default:
+		text = convertColorCodes(text, c.config.ColorMap, c.config.AmpersandCodes)
 	}
 
-	return commands
+	// Collapse multi-line responses into a single line, after stripping
+	// so that whitespace next to color codes is trimmed too
+	if c.config.OneLine {
+		text = strings.Join(strings.Fields(text), " ")
+	}
+	return text
 }
 
 // printResponse prints the command response with optional color handling
 func (c *RCONClient) printResponse(text string) {
+	// The packet was already dumped when it was received
+	if c.config.RawHex {
+		return
+	}
+
 	if c.config.RawOutput {
 		fmt.Print(text)
 		return
 	}
 
-	// Strip Minecraft color codes if colors disabled
-	if c.config.DisableColors {
-		text = stripColorCodes(text)
-	} else {
-		text = convertColorCodes(text)
-	}
+	text = c.renderText(text)
 
 	fmt.Print(text)
 	if !strings.HasSuffix(text, "\n") {
@@ -0,0 +1,1584 @@
+package mcrcon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/chzyer/readline"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestAuthenticate(t *testing.T) {
+	client := newTestClient(t, &Config{Password: testPassword}, rconHandler(echo))
+	if err := client.Authenticate(); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	body, err := client.Send("list")
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if body != "list" {
+		t.Errorf("Send returned %q, want %q", body, "list")
+	}
+}
+
+func TestAuthenticateWrongPassword(t *testing.T) {
+	client := newTestClient(t, &Config{Password: "wrong"}, rconHandler(echo))
+	if err := client.Authenticate(); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Authenticate returned %v, want ErrAuthFailed", err)
+	}
+}
+
+func TestAuthenticateSkipsEmptyValuePacket(t *testing.T) {
+	// Source engine servers send an empty value packet before the auth response
+	handle := rconHandler(echo)
+	client := newTestClient(t, &Config{Password: testPassword}, func(p *RCONPacket) []*RCONPacket {
+		if p.Type == rconAuthenticate {
+			return append([]*RCONPacket{{ID: p.ID, Type: rconResponseValue}}, handle(p)...)
+		}
+		return handle(p)
+	})
+	if err := client.Authenticate(); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	if body, err := client.Send("list"); err != nil || body != "list" {
+		t.Errorf("Send after a two-packet auth = %q, %v, want %q", body, err, "list")
+	}
+}
+
+func TestCommandSizeBoundary(t *testing.T) {
+	sizes := make(chan int32, 1)
+	handle := rconHandler(echo)
+	client := newAuthenticatedClient(t, &Config{}, func(p *RCONPacket) []*RCONPacket {
+		if p.Type == rconExecCommand {
+			sizes <- p.Size
+		}
+		return handle(p)
+	})
+
+	command := strings.Repeat("a", maxCommandSize)
+	body, err := client.Send(command)
+	if err != nil {
+		t.Fatalf("Send with a %d byte command: %v", len(command), err)
+	}
+	if body != command {
+		t.Errorf("%d byte command didn't round-trip", len(command))
+	}
+
+	// The packet of the largest command fills the server's buffer exactly,
+	// leaving room for the size field's terminator
+	if size := <-sizes; size != dataBuffSize-1 {
+		t.Errorf("largest command packet has Size %d, want %d", size, dataBuffSize-1)
+	}
+
+	if _, err := client.Send(command + "a"); err == nil || !strings.Contains(err.Error(), "too long") {
+		t.Errorf("Send with a %d byte command returned %v, want a too long error", len(command)+1, err)
+	}
+}
+
+func TestMultibyteCommandSize(t *testing.T) {
+	client := newAuthenticatedClient(t, &Config{}, rconHandler(echo))
+
+	// The limit is in bytes on the wire, not characters
+	command := strings.Repeat("é", maxCommandSize/2+1)
+	if _, err := client.Send(command); err == nil {
+		t.Errorf("Send accepted a %d byte command", len(command))
+	}
+}
+
+func TestDialIPv6Loopback(t *testing.T) {
+	ln, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	_, port := serveListener(t, ln, rconHandler(echo), nil)
+
+	for _, host := range []string{"::1", "[::1]"} {
+		client, err := NewRCONClient(&Config{Host: host, Port: port, Password: testPassword})
+		if err != nil {
+			t.Fatalf("NewRCONClient with host %q: %v", host, err)
+		}
+		if err := client.Authenticate(); err != nil {
+			t.Errorf("Authenticate over %q: %v", host, err)
+		}
+		client.Close()
+	}
+}
+
+func TestNotConnected(t *testing.T) {
+	client, err := New("localhost", WithPassword(testPassword))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if client.IsConnected() {
+		t.Error("IsConnected = true before connecting")
+	}
+	if err := client.Authenticate(); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Authenticate returned %v, want ErrNotConnected", err)
+	}
+	if _, err := client.Send("list"); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Send returned %v, want ErrNotConnected", err)
+	}
+	if _, _, err := client.SendPipelined([]string{"list"}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("SendPipelined returned %v, want ErrNotConnected", err)
+	}
+	if _, err := client.SendRaw(7, "ping"); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("SendRaw returned %v, want ErrNotConnected", err)
+	}
+	if _, err := client.SendPacket("list"); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("SendPacket returned %v, want ErrNotConnected", err)
+	}
+
+	client.config.Drain = true
+	if _, err := client.Send("list"); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Send with Drain returned %v, want ErrNotConnected", err)
+	}
+}
+
+func TestIsConnected(t *testing.T) {
+	var stops atomic.Int32
+	client := newAuthenticatedClient(t, &Config{}, stopHandler(&stops))
+	if !client.IsConnected() {
+		t.Fatal("IsConnected = false after authenticating")
+	}
+
+	// The server drops the connection instead of answering stop
+	if _, err := client.Send("stop"); !errors.Is(err, ErrConnClosed) {
+		t.Fatalf("Send returned %v, want ErrConnClosed", err)
+	}
+	if client.IsConnected() {
+		t.Error("IsConnected = true after the server dropped the connection")
+	}
+
+	if err := client.Reauthenticate(); err != nil {
+		t.Fatalf("Reauthenticate: %v", err)
+	}
+	if !client.IsConnected() {
+		t.Error("IsConnected = false after reconnecting")
+	}
+
+	client.Close()
+	if client.IsConnected() {
+		t.Error("IsConnected = true after Close")
+	}
+}
+
+func TestConfiguredRequestID(t *testing.T) {
+	var ids []int32
+	handle := rconHandler(echo)
+	client := newAuthenticatedClient(t, &Config{RequestID: 1000}, func(p *RCONPacket) []*RCONPacket {
+		ids = append(ids, p.ID)
+		return handle(p)
+	})
+
+	for _, command := range []string{"list", "seed"} {
+		if _, err := client.Send(command); err != nil {
+			t.Fatalf("Send(%q): %v", command, err)
+		}
+	}
+
+	// The server echoes each ID back, which Send checks against the ID it sent
+	want := []int32{1000, 1001, 1002}
+	if !slices.Equal(ids, want) {
+		t.Errorf("server saw request IDs %v, want %v", ids, want)
+	}
+}
+
+func TestReauthRequired(t *testing.T) {
+	tests := []struct {
+		name          string
+		autoReconnect bool
+		auths         int32
+	}{
+		{"report", false, 1},
+		{"reauthenticate", true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The first command is refused as if the session had expired
+			var auths, commands atomic.Int32
+			handle := rconHandler(echo)
+			client := newAuthenticatedClient(t, &Config{AutoReconnect: tt.autoReconnect}, func(p *RCONPacket) []*RCONPacket {
+				if p.Type == rconAuthenticate {
+					auths.Add(1)
+				} else if commands.Add(1) == 1 {
+					return []*RCONPacket{{ID: -1, Type: rconResponseValue}}
+				}
+				return handle(p)
+			})
+
+			var body string
+			var err error
+			captureStderr(t, func() { body, err = client.sendReconnecting("list") })
+			if tt.autoReconnect {
+				if err != nil || body != "list" {
+					t.Errorf("sendReconnecting = %q, %v, want the response", body, err)
+				}
+			} else if !errors.Is(err, ErrReauthRequired) {
+				t.Errorf("sendReconnecting returned %v, want ErrReauthRequired", err)
+			}
+			if n := auths.Load(); n != tt.auths {
+				t.Errorf("authenticated %d times, want %d", n, tt.auths)
+			}
+		})
+	}
+}
+
+func TestSendRaw(t *testing.T) {
+	// The server answers a packet of an unknown type with another unknown
+	// type and an unrelated ID, which SendRaw passes through unchecked
+	received := make(chan *RCONPacket, 1)
+	client := newPipeClient(t, &Config{}, func(p *RCONPacket) []*RCONPacket {
+		received <- p
+		return []*RCONPacket{{ID: 42, Type: 9, Body: "unknown type"}}
+	})
+
+	response, err := client.SendRaw(7, "ping")
+	if err != nil {
+		t.Fatalf("SendRaw: %v", err)
+	}
+	if p := <-received; p.Type != 7 || p.Body != "ping" {
+		t.Errorf("server received type %d body %q, want type 7 body %q", p.Type, p.Body, "ping")
+	}
+	if response.ID != 42 || response.Type != 9 || response.Body != "unknown type" {
+		t.Errorf("SendRaw returned %+v, want the server's packet", response)
+	}
+}
+
+func TestAuthQuirkNoDesync(t *testing.T) {
+	// Source-derived servers send an empty value packet alongside the
+	// auth response, in either order
+	empty := func(p *RCONPacket) *RCONPacket { return &RCONPacket{ID: p.ID, Type: rconResponseValue} }
+	auth := func(p *RCONPacket) *RCONPacket { return &RCONPacket{ID: p.ID, Type: rconAuthResponse} }
+
+	tests := []struct {
+		name    string
+		packets func(p *RCONPacket) []*RCONPacket
+	}{
+		{"before", func(p *RCONPacket) []*RCONPacket { return []*RCONPacket{empty(p), auth(p)} }},
+		{"after", func(p *RCONPacket) []*RCONPacket { return []*RCONPacket{auth(p), empty(p)} }},
+		{"two after", func(p *RCONPacket) []*RCONPacket { return []*RCONPacket{auth(p), empty(p), empty(p)} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handle := rconHandler(echo)
+			client := newTestClient(t, &Config{}, func(p *RCONPacket) []*RCONPacket {
+				if p.Type == rconAuthenticate {
+					return tt.packets(p)
+				}
+				return handle(p)
+			})
+
+			if err := client.Authenticate(); err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			for _, command := range []string{"first", "second"} {
+				if body, err := client.Send(command); err != nil || body != command {
+					t.Errorf("Send(%q) = %q, %v, want its own response", command, body, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSendPacket(t *testing.T) {
+	// The server answers with a type of its own and a body longer than
+	// MaxBodyBytes
+	requests := make(chan int32, 2)
+	handle := rconHandler(echo)
+	client := newAuthenticatedClient(t, &Config{MaxBodyBytes: 4}, func(p *RCONPacket) []*RCONPacket {
+		if p.Type != rconExecCommand {
+			return handle(p)
+		}
+		requests <- p.ID
+		return []*RCONPacket{{ID: p.ID, Type: 7, Body: "0123456789"}}
+	})
+
+	response, err := client.SendPacket("list")
+	if err != nil {
+		t.Fatalf("SendPacket: %v", err)
+	}
+	if id := <-requests; response.ID != id {
+		t.Errorf("ID = %d, want the request's %d", response.ID, id)
+	}
+	if response.Type != 7 {
+		t.Errorf("Type = %d, want 7", response.Type)
+	}
+	if response.Body != "0123456789" {
+		t.Errorf("Body = %q, want the whole body", response.Body)
+	}
+	if want := int32(len(response.Body) + packetOverhead); response.Size != want {
+		t.Errorf("Size = %d, want %d", response.Size, want)
+	}
+
+	// Send still shortens the body
+	if body, err := client.Send("list"); err != nil || !strings.HasPrefix(body, "0123") || strings.HasPrefix(body, "01234") {
+		t.Errorf("Send = %q, %v, want the body shortened to 4 bytes", body, err)
+	}
+}
+
+func TestUnexpectedResponseID(t *testing.T) {
+	client := newAuthenticatedClient(t, &Config{RequestID: 1000}, func(p *RCONPacket) []*RCONPacket {
+		if p.Type == rconAuthenticate {
+			return []*RCONPacket{{ID: p.ID, Type: rconAuthResponse}}
+		}
+		return []*RCONPacket{{ID: 5, Type: rconResponseValue, Body: "§cUnknown request"}}
+	})
+
+	_, err := client.Send("list")
+	if err == nil {
+		t.Fatal("Send accepted a response with another request's ID")
+	}
+	for _, want := range []string{"1001", "5", "Unknown request"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't contain %q", err, want)
+		}
+	}
+}
+
+func TestStaleResponseDiscarded(t *testing.T) {
+	client := newAuthenticatedClient(t, &Config{}, func(p *RCONPacket) []*RCONPacket {
+		if p.Type == rconAuthenticate {
+			return []*RCONPacket{{ID: p.ID, Type: rconAuthResponse}}
+		}
+		// A late response to the previous request arrives first
+		return []*RCONPacket{
+			{ID: p.ID - 1, Type: rconResponseValue, Body: "stale"},
+			{ID: p.ID, Type: rconResponseValue, Body: p.Body},
+		}
+	})
+
+	if body, err := client.Send("list"); err != nil || body != "list" {
+		t.Errorf("Send = %q, %v, want %q", body, err, "list")
+	}
+}
+
+func TestRequestIDWraps(t *testing.T) {
+	client := &RCONClient{config: &Config{RequestID: 7}, nextID: math.MaxInt32}
+
+	if id := client.requestID(); id != math.MaxInt32 {
+		t.Errorf("requestID = %d, want %d", id, math.MaxInt32)
+	}
+	if id := client.requestID(); id != 7 {
+		t.Errorf("requestID after MaxInt32 = %d, want the base ID 7", id)
+	}
+}
+
+func TestReconnectAfterDrop(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted := make(chan net.Conn, 2)
+	host, port := serveListener(t, ln, rconHandler(echo), accepted)
+
+	config := &Config{Host: host, Port: port, Password: testPassword, AutoReconnect: true, SilentMode: true}
+	client, err := NewRCONClient(config)
+	if err != nil {
+		t.Fatalf("NewRCONClient: %v", err)
+	}
+	defer client.Close()
+	if err := client.Authenticate(); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if err := client.ExecuteCommand("list"); err != nil {
+		t.Fatalf("ExecuteCommand before the drop: %v", err)
+	}
+
+	// Reset the connection mid-session, as a network blip would
+	conn := <-accepted
+	conn.(*net.TCPConn).SetLinger(0)
+	conn.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	if err := client.ExecuteCommand("list"); err != nil {
+		t.Fatalf("ExecuteCommand after the drop: %v", err)
+	}
+	select {
+	case conn = <-accepted:
+	default:
+		t.Fatal("the client didn't reconnect")
+	}
+
+	config.AutoReconnect = false
+	conn.(*net.TCPConn).SetLinger(0)
+	conn.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	if err := client.ExecuteCommand("list"); !errors.Is(err, ErrConnClosed) {
+		t.Errorf("ExecuteCommand without AutoReconnect returned %v, want ErrConnClosed", err)
+	}
+}
+
+func TestSendReusesConnection(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted := make(chan net.Conn, 2)
+	host, port := serveListener(t, ln, rconHandler(echo), accepted)
+
+	client, err := NewRCONClient(&Config{Host: host, Port: port, Password: testPassword})
+	if err != nil {
+		t.Fatalf("NewRCONClient: %v", err)
+	}
+	defer client.Close()
+	if err := client.Authenticate(); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	for _, command := range []string{"list", "seed", "time query daytime", "difficulty"} {
+		if body, err := client.Send(command); err != nil || body != command {
+			t.Fatalf("Send(%q) = %q, %v", command, body, err)
+		}
+	}
+	if n := len(accepted); n != 1 {
+		t.Errorf("the commands used %d connections, want 1", n)
+	}
+
+	// Reauthenticate starts a new session that also takes commands
+	if err := client.Reauthenticate(); err != nil {
+		t.Fatalf("Reauthenticate: %v", err)
+	}
+	if body, err := client.Send("list"); err != nil || body != "list" {
+		t.Errorf("Send after Reauthenticate = %q, %v", body, err)
+	}
+	if n := len(accepted); n != 2 {
+		t.Errorf("got %d connections after Reauthenticate, want 2", n)
+	}
+}
+
+func TestConnectAfterClose(t *testing.T) {
+	client := newAuthenticatedClient(t, &Config{}, rconHandler(echo))
+	client.Close()
+
+	if err := client.Connect(); !errors.Is(err, ErrConnClosed) {
+		t.Errorf("Connect after Close returned %v, want ErrConnClosed", err)
+	}
+}
+
+func TestNoRetryDialsOnce(t *testing.T) {
+	// Find a port with nothing listening on it
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+
+	var logs bytes.Buffer
+	config := &Config{
+		Host:    host,
+		Port:    port,
+		NoRetry: true,
+		Logger:  slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+
+	start := time.Now()
+	if _, err := NewRCONClient(config); err == nil {
+		t.Fatal("NewRCONClient connected to a closed port")
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("NewRCONClient took %v to fail, want no pause for a retry", elapsed)
+	}
+	if n := strings.Count(logs.String(), "msg=dialing"); n != 1 {
+		t.Errorf("made %d dial attempts, want 1", n)
+	}
+}
+
+func TestTranscript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.log")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	client := newAuthenticatedClient(t, &Config{Transcript: f, SilentMode: true}, rconHandler(func(command string) string {
+		if command == "list" {
+			return "§aThere are 0 players online"
+		}
+		return ""
+	}))
+	for _, command := range []string{"list", "save-all"} {
+		if err := client.ExecuteCommand(command); err != nil {
+			t.Fatalf("ExecuteCommand(%q): %v", command, err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	want := []string{"> list", "There are 0 players online", "> save-all"}
+	if len(lines) != len(want) {
+		t.Fatalf("transcript = %q, want entries for %q", data, want)
+	}
+	for i, line := range lines {
+		// Command lines start with a timestamp
+		if strings.HasPrefix(want[i], ">") {
+			if _, rest, ok := strings.Cut(line, "] "); !ok || rest != want[i] || !strings.HasPrefix(line, "[") {
+				t.Errorf("transcript line %d = %q, want a timestamped %q", i, line, want[i])
+			}
+		} else if line != want[i] {
+			t.Errorf("transcript line %d = %q, want %q", i, line, want[i])
+		}
+	}
+}
+
+func TestTranscriptColorTags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.log")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	client := newAuthenticatedClient(t, &Config{Transcript: f, SilentMode: true, ColorTags: true}, rconHandler(func(command string) string {
+		return "§aThere are 0 players online"
+	}))
+	if err := client.ExecuteCommand("list"); err != nil {
+		t.Fatalf("ExecuteCommand: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\n[green]There are 0 players online[/green]\n"; !strings.HasSuffix(string(data), want) {
+		t.Errorf("transcript = %q, want the response ending in %q", data, want)
+	}
+}
+
+// stopHandler answers commands like a server that shuts down on stop,
+// closing the connection without a response, and counts the stops
+func stopHandler(stops *atomic.Int32) handlerFunc {
+	handle := rconHandler(echo)
+	return func(p *RCONPacket) []*RCONPacket {
+		if p.Type == rconExecCommand && p.Body == "stop" {
+			stops.Add(1)
+			return []*RCONPacket{hangUp}
+		}
+		return handle(p)
+	}
+}
+
+func TestServerClosesAfterSend(t *testing.T) {
+	var stops atomic.Int32
+	client := newAuthenticatedClient(t, &Config{}, stopHandler(&stops))
+
+	_, err := client.Send("stop")
+	if !errors.Is(err, ErrClosedAfterSend) || !errors.Is(err, ErrConnClosed) {
+		t.Errorf("Send returned %v, want ErrClosedAfterSend and ErrConnClosed", err)
+	}
+}
+
+func TestRunCommandsStopLast(t *testing.T) {
+	var stops atomic.Int32
+	client := newAuthenticatedClient(t, &Config{SilentMode: true, AutoReconnect: true}, stopHandler(&stops))
+
+	if status := client.RunCommands([]string{"save-all", "stop"}); status != 0 {
+		t.Errorf("RunCommands with a final stop returned %d, want 0", status)
+	}
+	// The server got the stop, so reconnecting must not send it again
+	if n := stops.Load(); n != 1 {
+		t.Errorf("server received stop %d times, want 1", n)
+	}
+}
+
+func TestRunCommandsStopNotLast(t *testing.T) {
+	var stops atomic.Int32
+	client := newAuthenticatedClient(t, &Config{SilentMode: true}, stopHandler(&stops))
+
+	if status := client.RunCommands([]string{"stop", "list"}); status == 0 {
+		t.Error("RunCommands succeeded although the connection closed before the last command")
+	}
+}
+
+func TestConcurrentSend(t *testing.T) {
+	client := newAuthenticatedClient(t, &Config{}, rconHandler(echo))
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			command := fmt.Sprintf("say %d", i)
+			body, err := client.Send(command)
+			if err != nil {
+				t.Errorf("Send(%q): %v", command, err)
+			} else if body != command {
+				t.Errorf("Send(%q) = %q", command, body)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRetryTimeouts(t *testing.T) {
+	// The server is slow to answer the first command, so its response
+	// misses the deadline, and answers the next one at once
+	var commands atomic.Int32
+	handle := rconHandler(echo)
+	client := newAuthenticatedClient(t, &Config{TimeoutRetries: 1, Timeout: 100 * time.Millisecond}, func(p *RCONPacket) []*RCONPacket {
+		if p.Type == rconExecCommand && commands.Add(1) == 1 {
+			return nil
+		}
+		return handle(p)
+	})
+
+	body, err := client.sendReconnecting("list")
+	if err != nil {
+		t.Fatalf("sendReconnecting: %v", err)
+	}
+	if body != "list" {
+		t.Errorf("response = %q, want %q", body, "list")
+	}
+	if n := commands.Load(); n != 2 {
+		t.Errorf("server received the command %d times, want 2", n)
+	}
+}
+
+func TestCommandPrefix(t *testing.T) {
+	received := make(chan string, 1)
+	handle := rconHandler(echo)
+	client := newAuthenticatedClient(t, &Config{SilentMode: true, CommandPrefix: "execute in world_nether run"}, func(p *RCONPacket) []*RCONPacket {
+		if p.Type == rconExecCommand {
+			received <- p.Body
+		}
+		return handle(p)
+	})
+
+	if err := client.ExecuteCommand("time set day"); err != nil {
+		t.Fatalf("ExecuteCommand: %v", err)
+	}
+	if body, want := <-received, "execute in world_nether run time set day"; body != want {
+		t.Errorf("server received %q, want %q", body, want)
+	}
+}
+
+// recordingHandler echoes commands like rconHandler(echo), sending each
+// command it receives on commands
+func recordingHandler(commands chan<- string) handlerFunc {
+	handle := rconHandler(echo)
+	return func(p *RCONPacket) []*RCONPacket {
+		if p.Type == rconExecCommand {
+			commands <- p.Body
+		}
+		return handle(p)
+	}
+}
+
+func TestRunCommandsKeepGoing(t *testing.T) {
+	tests := []struct {
+		keepGoing bool
+		want      []string
+	}{
+		{false, []string{"save-all", "fail"}},
+		{true, []string{"save-all", "fail", "list"}},
+	}
+
+	for _, tt := range tests {
+		commands := make(chan string, 3)
+		config := &Config{SilentMode: true, KeepGoing: tt.keepGoing, FailOn: regexp.MustCompile("fail")}
+		client := newAuthenticatedClient(t, config, recordingHandler(commands))
+
+		if status := client.RunCommands([]string{"save-all", "fail", "list"}); status == 0 {
+			t.Errorf("KeepGoing=%v: RunCommands succeeded with a failing command", tt.keepGoing)
+		}
+		client.Close()
+		close(commands)
+
+		var got []string
+		for command := range commands {
+			got = append(got, command)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("KeepGoing=%v: server received %q, want %q", tt.keepGoing, got, tt.want)
+		}
+	}
+}
+
+func TestRunRepeated(t *testing.T) {
+	commands := make(chan string, 10)
+	client := newAuthenticatedClient(t, &Config{SilentMode: true}, recordingHandler(commands))
+
+	var code int
+	stderr := captureStderr(t, func() {
+		code = client.RunRepeated([]string{"list", "tps"}, 3)
+	})
+	close(commands)
+
+	if code != 0 {
+		t.Errorf("RunRepeated returned %d, want 0", code)
+	}
+	var got []string
+	for command := range commands {
+		got = append(got, command)
+	}
+	if want := []string{"list", "tps", "list", "tps", "list", "tps"}; !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+	if want := "6 commands sent: 6 succeeded, 0 failed\n"; stderr != want {
+		t.Errorf("summary = %q, want %q", stderr, want)
+	}
+}
+
+func TestTiming(t *testing.T) {
+	client := newAuthenticatedClient(t, &Config{Timing: true}, rconHandler(echo))
+
+	var stdout string
+	stderr := captureStderr(t, func() {
+		stdout = captureStdout(t, func() {
+			if err := client.ExecuteCommand("list"); err != nil {
+				t.Errorf("ExecuteCommand: %v", err)
+			}
+		})
+	})
+
+	if !strings.HasPrefix(stdout, "list") {
+		t.Errorf("stdout = %q, want the response", stdout)
+	}
+	if !regexp.MustCompile(`^\[\d+\.\d\dms\]\n$`).MatchString(stderr) {
+		t.Errorf("stderr = %q, want a timing line", stderr)
+	}
+}
+
+func TestMaxCommands(t *testing.T) {
+	commands := make(chan string, 3)
+	client := newAuthenticatedClient(t, &Config{SilentMode: true, MaxCommands: 2}, recordingHandler(commands))
+
+	var code int
+	stderr := captureStderr(t, func() { code = client.RunCommands([]string{"list", "seed", "stop"}) })
+
+	if code != 1 {
+		t.Errorf("RunCommands returned %d, want 1", code)
+	}
+	if want := "3 commands given, more than the limit of 2; nothing was run"; !strings.Contains(stderr, want) {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+	if len(commands) != 0 {
+		t.Errorf("server received %q", <-commands)
+	}
+}
+
+func TestEchoCommand(t *testing.T) {
+	reply := func(command string) string { return "response to " + command }
+
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{"echo", Config{EchoCommand: true, DisableColors: true}, "$ list\nresponse to list\n$ seed\nresponse to seed\n"},
+		{"off", Config{DisableColors: true}, "response to list\nresponse to seed\n"},
+		{"silent", Config{EchoCommand: true, SilentMode: true}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newAuthenticatedClient(t, &tt.config, rconHandler(reply))
+			stdout := captureStdout(t, func() {
+				if code := client.RunCommands([]string{"list", "seed"}); code != 0 {
+					t.Errorf("RunCommands returned %d, want 0", code)
+				}
+			})
+			if stdout != tt.want {
+				t.Errorf("stdout = %q, want %q", stdout, tt.want)
+			}
+		})
+	}
+}
+
+func TestDrainStrayPacket(t *testing.T) {
+	// The response to the first command is followed by a stray packet, as
+	// if the rest of an earlier response arrived late
+	handle := rconHandler(echo)
+	strayHandler := func(p *RCONPacket) []*RCONPacket {
+		packets := handle(p)
+		if p.Body == "first" {
+			packets = append(packets, &RCONPacket{ID: 999, Type: rconResponseValue, Body: "stray"})
+		}
+		return packets
+	}
+
+	for _, drain := range []bool{false, true} {
+		client := newAuthenticatedClient(t, &Config{Drain: drain}, strayHandler)
+		if _, err := client.Send("first"); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+
+		body, err := client.Send("second")
+		if drain && (err != nil || body != "second") {
+			t.Errorf("with Drain, Send = %q, %v, want %q", body, err, "second")
+		}
+		if !drain && err == nil {
+			t.Errorf("without Drain, Send = %q, want an unexpected ID error", body)
+		}
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	commands := make(chan string, 16)
+	client := newAuthenticatedClient(t, &Config{}, recordingHandler(commands))
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		client.heartbeat(20*time.Millisecond, stop)
+		close(done)
+	}()
+
+	select {
+	case command := <-commands:
+		if command != "" {
+			t.Errorf("heartbeat sent %q, want an empty command", command)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("no heartbeat was sent")
+	}
+
+	close(stop)
+	<-done
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	client := newAuthenticatedClient(t, &Config{MaxBodyBytes: 10}, rconHandler(func(command string) string {
+		if command == "long" {
+			return strings.Repeat("a", 4000)
+		}
+		return command
+	}))
+
+	body, err := client.Send("long")
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if want := "aaaaaaaaaa" + truncatedMarker; body != want {
+		t.Errorf("Send = %q, want %q", body, want)
+	}
+
+	// The rest of the long packet was read, so the next response matches
+	if body, err := client.Send("next"); err != nil || body != "next" {
+		t.Errorf("Send after truncation = %q, %v, want %q", body, err, "next")
+	}
+}
+
+func TestTruncateBody(t *testing.T) {
+	tests := []struct {
+		body string
+		max  int
+		want string
+	}{
+		{"hello", 0, "hello"},
+		{"hello", 5, "hello"},
+		{"hello", 4, "hell" + truncatedMarker},
+		{"héllo", 2, "h" + truncatedMarker}, // doesn't split é
+	}
+
+	for _, tt := range tests {
+		if got := truncateBody(tt.body, tt.max); got != tt.want {
+			t.Errorf("truncateBody(%q, %d) = %q, want %q", tt.body, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestKeepGoingStatus(t *testing.T) {
+	tests := []struct {
+		commands []string
+		want     int
+	}{
+		{[]string{"list", "save-all"}, 0},
+		{[]string{"fail", "list"}, 11},
+		{[]string{"fail", "list", "fail", "fail"}, 13},
+	}
+
+	for _, tt := range tests {
+		config := &Config{SilentMode: true, KeepGoing: true, FailOn: regexp.MustCompile("fail")}
+		client := newAuthenticatedClient(t, config, rconHandler(echo))
+		captureStderr(t, func() {
+			if status := client.RunCommands(tt.commands); status != tt.want {
+				t.Errorf("RunCommands(%q) = %d, want %d", tt.commands, status, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	tests := []struct {
+		failed             int
+		aborted, keepGoing bool
+		want               int
+	}{
+		{0, false, false, 0},
+		{1, false, false, 1},
+		{1, true, true, 1}, // interrupted
+		{2, false, true, 12},
+		{500, false, true, maxFailureStatus},
+	}
+
+	for _, tt := range tests {
+		if got := exitStatus(tt.failed, tt.aborted, tt.keepGoing); got != tt.want {
+			t.Errorf("exitStatus(%d, %v, %v) = %d, want %d", tt.failed, tt.aborted, tt.keepGoing, got, tt.want)
+		}
+	}
+}
+
+func TestSignalInterruptsWait(t *testing.T) {
+	commands := make(chan string, 2)
+	client := newAuthenticatedClient(t, &Config{SilentMode: true, Wait: time.Hour}, recordingHandler(commands))
+
+	// Close the client on SIGINT, as the command-line tool does
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		client.Close()
+	}()
+
+	status := make(chan int, 1)
+	go func() {
+		status <- client.RunCommands([]string{"say Restarting in 15 minutes", "stop"})
+	}()
+
+	<-commands
+	time.Sleep(50 * time.Millisecond) // let RunCommands start waiting
+	syscall.Kill(os.Getpid(), syscall.SIGINT)
+
+	select {
+	case code := <-status:
+		if code == 0 {
+			t.Error("RunCommands succeeded although it was interrupted")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGINT didn't interrupt the wait")
+	}
+	if len(commands) != 0 {
+		t.Errorf("server received %q after the interrupt", <-commands)
+	}
+}
+
+func TestNewClientWithConn(t *testing.T) {
+	client := newPipeClient(t, &Config{Password: testPassword}, rconHandler(strings.ToUpper))
+	if err := client.Authenticate(); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	body, err := client.Send("list")
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if body != "LIST" {
+		t.Errorf("response = %q, want %q", body, "LIST")
+	}
+}
+
+func TestConfigDialer(t *testing.T) {
+	dialer := &pipeDialer{handle: rconHandler(echo)}
+	defer dialer.close()
+
+	client, err := NewRCONClient(&Config{Host: "mc.example.com", Port: "25575", Password: testPassword, Dialer: dialer})
+	if err != nil {
+		t.Fatalf("NewRCONClient: %v", err)
+	}
+	defer client.Close()
+
+	// Reconnecting dials through the same Dialer
+	if err := client.Reauthenticate(); err != nil {
+		t.Fatalf("Reauthenticate: %v", err)
+	}
+	if body, err := client.Send("list"); err != nil || body != "list" {
+		t.Errorf("Send = %q, %v, want %q", body, err, "list")
+	}
+
+	want := []string{"mc.example.com:25575", "mc.example.com:25575"}
+	if got := dialer.dialed(); !slices.Equal(got, want) {
+		t.Errorf("dialed %q, want %q", got, want)
+	}
+}
+
+func TestOneLine(t *testing.T) {
+	tests := []struct {
+		response string
+		want     string
+	}{
+		{"There are 0 of a max of 20 players online: ", "There are 0 of a max of 20 players online:\n"},
+		{"Line one\nLine two\n", "Line one Line two\n"},
+		{"  padded \t\r\n\n  text  \n", "padded text\n"},
+		{"§6Help: Index (1/9) ----\n§r§6Use /help [n] to get page n of help.  \n", "Help: Index (1/9) ---- Use /help [n] to get page n of help.\n"},
+		{"§a \n", "\n"},
+	}
+
+	for _, tt := range tests {
+		client := &RCONClient{config: &Config{OneLine: true, DisableColors: true}}
+		got := captureStdout(t, func() { client.printResponse(tt.response) })
+		if got != tt.want {
+			t.Errorf("printResponse(%q) printed %q, want %q", tt.response, got, tt.want)
+		}
+	}
+}
+
+func TestPrintResponseNullsAndCRLF(t *testing.T) {
+	tests := []struct {
+		response string
+		want     string
+	}{
+		{"Saved the game\x00\x00", "Saved the game\n"},
+		{"Line one\r\nLine two\r\n", "Line one\nLine two\n"},
+		{"kept\x00inside\x00", "kept\x00inside\n"},
+	}
+
+	for _, tt := range tests {
+		client := &RCONClient{config: &Config{DisableColors: true}}
+		got := captureStdout(t, func() { client.printResponse(tt.response) })
+		if got != tt.want {
+			t.Errorf("printResponse(%q) printed %q, want %q", tt.response, got, tt.want)
+		}
+	}
+}
+
+func TestSRV(t *testing.T) {
+	tests := []struct {
+		name   string
+		port   string
+		rcode  dnsmessage.RCode
+		want   string
+		lookup bool
+	}{
+		{"record", "", dnsmessage.RCodeSuccess, "rcon.example.net:25580", true},
+		{"no record", "", dnsmessage.RCodeNameError, "mc.example.com:25575", true},
+		{"explicit port", "25590", dnsmessage.RCodeSuccess, "mc.example.com:25590", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lookups atomic.Int32
+			resolver := stubResolver(func(q dnsmessage.Question) ([]dnsmessage.Resource, dnsmessage.RCode) {
+				lookups.Add(1)
+				if q.Type != dnsmessage.TypeSRV || q.Name.String() != "_rcon._tcp.mc.example.com." || tt.rcode != dnsmessage.RCodeSuccess {
+					return nil, tt.rcode
+				}
+				return []dnsmessage.Resource{srvRecord(q, "rcon.example.net.", 25580)}, dnsmessage.RCodeSuccess
+			})
+			dialer := &pipeDialer{handle: rconHandler(echo)}
+			defer dialer.close()
+
+			config := &Config{Host: "mc.example.com", Port: tt.port, SRV: true, Resolver: resolver, Dialer: dialer}
+			client, err := NewRCONClient(config)
+			if err != nil {
+				t.Fatalf("NewRCONClient: %v", err)
+			}
+			client.Close()
+
+			if got := dialer.dialed(); !slices.Equal(got, []string{tt.want}) {
+				t.Errorf("dialed %q, want %q", got, tt.want)
+			}
+			if looked := lookups.Load() > 0; looked != tt.lookup {
+				t.Errorf("looked up the SRV record = %v, want %v", looked, tt.lookup)
+			}
+		})
+	}
+}
+
+func TestUnresolvableHost(t *testing.T) {
+	tests := []struct {
+		name   string
+		rcode  dnsmessage.RCode
+		pauses int
+	}{
+		{"unknown host", dnsmessage.RCodeNameError, 0},
+		{"server failure", dnsmessage.RCodeServerFailure, dialAttempts - 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := stubResolver(func(q dnsmessage.Question) ([]dnsmessage.Resource, dnsmessage.RCode) {
+				return nil, tt.rcode
+			})
+			clock := &fakeClock{}
+			_, err := dial(&Config{Host: "mc.example.com", Port: "25575", Resolver: resolver, Clock: clock})
+
+			var dnsErr *net.DNSError
+			if !errors.As(err, &dnsErr) {
+				t.Fatalf("dial returned %v, want a DNS error", err)
+			}
+			if notFound := strings.HasPrefix(err.Error(), "could not resolve host mc.example.com"); notFound != dnsErr.IsNotFound {
+				t.Errorf("error = %q for a DNS error with IsNotFound %v", err, dnsErr.IsNotFound)
+			}
+			if got := len(clock.recorded()); got != tt.pauses {
+				t.Errorf("paused %d times between attempts, want %d", got, tt.pauses)
+			}
+		})
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	path := t.TempDir() + "/rcon.sock"
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("Unix sockets unavailable: %v", err)
+	}
+	serveListener(t, ln, rconHandler(echo), nil)
+
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"option", Config{UnixSocket: path}},
+		{"host", Config{Host: "unix://" + path}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Password = testPassword
+			client, err := NewRCONClient(&tt.config)
+			if err != nil {
+				t.Fatalf("NewRCONClient: %v", err)
+			}
+			defer client.Close()
+
+			if err := client.Authenticate(); err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if body, err := client.Send("list"); err != nil || body != "list" {
+				t.Errorf("Send = %q, %v, want the response", body, err)
+			}
+		})
+	}
+}
+
+func TestRemoteAddr(t *testing.T) {
+	config := &Config{}
+	client := newTestClient(t, config, rconHandler(echo))
+	if got, want := client.RemoteAddr().String(), net.JoinHostPort(config.Host, config.Port); got != want {
+		t.Errorf("RemoteAddr = %s, want %s", got, want)
+	}
+
+	dryRun, err := NewRCONClient(&Config{Port: "25575", DryRun: true})
+	if err != nil {
+		t.Fatalf("NewRCONClient: %v", err)
+	}
+	if addr := dryRun.RemoteAddr(); addr != nil {
+		t.Errorf("RemoteAddr in dry-run mode = %v, want nil", addr)
+	}
+}
+
+func TestRunReader(t *testing.T) {
+	commands := make(chan string, 3)
+	client := newAuthenticatedClient(t, &Config{SilentMode: true}, recordingHandler(commands))
+
+	input := "say hello\n\n# a comment\n  save-all  \r\nlist"
+	if status := client.RunReader(strings.NewReader(input)); status != 0 {
+		t.Errorf("RunReader returned %d, want 0", status)
+	}
+	close(commands)
+
+	var got []string
+	for command := range commands {
+		got = append(got, command)
+	}
+	if want := []string{"say hello", "save-all", "list"}; !slices.Equal(got, want) {
+		t.Errorf("server received %q, want %q", got, want)
+	}
+}
+
+func TestRunReaderFailure(t *testing.T) {
+	client := newAuthenticatedClient(t, &Config{SilentMode: true, FailOn: regexp.MustCompile("fail")}, rconHandler(echo))
+
+	if status := client.RunReader(strings.NewReader("fail\nlist\n")); status == 0 {
+		t.Error("RunReader succeeded with a failing command")
+	}
+}
+
+func TestEmptyResponseMarker(t *testing.T) {
+	tests := []struct {
+		config *Config
+		want   string
+	}{
+		{&Config{}, ""},
+		{&Config{EmptyMarker: DefaultEmptyMarker}, "(no output)\n"},
+		{&Config{EmptyMarker: "ok"}, "ok\n"},
+		{&Config{EmptyMarker: "ok", SilentMode: true}, ""},
+	}
+
+	for _, tt := range tests {
+		client := newAuthenticatedClient(t, tt.config, rconHandler(func(string) string { return "" }))
+		var err error
+		got := captureStdout(t, func() { err = client.ExecuteCommand("time set day") })
+		if err != nil {
+			t.Errorf("ExecuteCommand: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("EmptyMarker %q, SilentMode %v printed %q, want %q", tt.config.EmptyMarker, tt.config.SilentMode, got, tt.want)
+		}
+	}
+}
+
+func TestLargeResponsePacket(t *testing.T) {
+	for _, n := range []int{4090, 4096} {
+		body := strings.Repeat("x", n)
+		client := newAuthenticatedClient(t, &Config{}, rconHandler(func(string) string { return body }))
+
+		got, err := client.Send("help")
+		if err != nil {
+			t.Errorf("Send with a %d-byte packet: %v", n+packetOverhead, err)
+		} else if got != body {
+			t.Errorf("Send with a %d-byte packet returned %d bytes", n+packetOverhead, len(got))
+		}
+	}
+}
+
+func TestOversizedResponsePacket(t *testing.T) {
+	body := strings.Repeat("x", dataBuffSize+1)
+	client := newAuthenticatedClient(t, &Config{}, rconHandler(func(string) string { return body }))
+
+	if _, err := client.Send("help"); err == nil || !strings.Contains(err.Error(), "invalid packet size") {
+		t.Errorf("Send returned %v, want an invalid packet size error", err)
+	}
+}
+
+func TestWaitForReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The server shuts down on stop and comes back a little later on the
+	// same address
+	stopped := make(chan struct{})
+	handle := rconHandler(echo)
+	host, port := serveListener(t, ln, func(p *RCONPacket) []*RCONPacket {
+		if p.Type == rconExecCommand && p.Body == "stop" {
+			ln.Close()
+			close(stopped)
+			return []*RCONPacket{hangUp}
+		}
+		return handle(p)
+	}, nil)
+
+	commands := make(chan string, 1)
+	go func() {
+		<-stopped
+		time.Sleep(200 * time.Millisecond)
+		restarted, err := net.Listen("tcp", ln.Addr().String())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		serveListener(t, restarted, recordingHandler(commands), nil)
+	}()
+
+	client, err := NewRCONClient(&Config{Host: host, Port: port, Password: testPassword, SilentMode: true, ReconnectWait: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("NewRCONClient: %v", err)
+	}
+	defer client.Close()
+	if err := client.Authenticate(); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	if status := client.RunCommands([]string{"save-all", "stop", "list"}); status != 0 {
+		t.Errorf("RunCommands returned %d, want 0", status)
+	}
+	select {
+	case command := <-commands:
+		if command != "list" {
+			t.Errorf("restarted server received %q, want %q", command, "list")
+		}
+	default:
+		t.Error("the command after stop didn't reach the restarted server")
+	}
+}
+
+// chunkedConn writes at most chunk bytes per Write call, as some
+// connections do under load
+type chunkedConn struct {
+	net.Conn
+	chunk int
+}
+
+func (c *chunkedConn) Write(b []byte) (int, error) {
+	return c.Conn.Write(b[:min(len(b), c.chunk)])
+}
+
+func TestChunkedWrites(t *testing.T) {
+	conn, server := net.Pipe()
+	serve(server, rconHandler(echo))
+	defer server.Close()
+
+	client := NewClientWithConn(&chunkedConn{Conn: conn, chunk: 3}, &Config{Password: testPassword})
+	defer client.Close()
+
+	if err := client.Authenticate(); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	command := "say " + strings.Repeat("chunked ", 100)
+	if body, err := client.Send(command); err != nil || body != command {
+		t.Errorf("Send = %q, %v, want the command echoed", body, err)
+	}
+}
+
+// stalledWriter accepts no bytes and reports no error
+type stalledWriter struct{}
+
+func (stalledWriter) Write([]byte) (int, error) { return 0, nil }
+
+func TestWriteFullStalled(t *testing.T) {
+	if err := writeFull(stalledWriter{}, []byte("list")); !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("writeFull returned %v, want io.ErrShortWrite", err)
+	}
+}
+
+func TestResponseHandler(t *testing.T) {
+	type response struct{ command, body string }
+	var got []response
+	config := &Config{
+		SilentMode: true,
+		ResponseHandler: func(command, body string) {
+			got = append(got, response{command, body})
+		},
+	}
+	client := newAuthenticatedClient(t, config, rconHandler(func(command string) string {
+		return "§a" + command
+	}))
+
+	out := captureStdout(t, func() {
+		if status := client.RunCommands([]string{"list", "seed"}); status != 0 {
+			t.Errorf("RunCommands returned %d, want 0", status)
+		}
+	})
+	if out != "" {
+		t.Errorf("printed %q with a ResponseHandler set", out)
+	}
+
+	want := []response{{"list", "§alist"}, {"seed", "§aseed"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("ResponseHandler received %q, want %q", got, want)
+	}
+}
+
+func TestBigEndian(t *testing.T) {
+	conn, server := net.Pipe()
+	defer server.Close()
+	client := NewClientWithConn(conn, &Config{BigEndian: true, RequestID: 0x01020304})
+	defer client.Close()
+
+	// The server checks the big-endian framing of the request and answers
+	// in kind
+	received := make(chan *RCONPacket, 1)
+	go func() {
+		header := make([]byte, 12)
+		if _, err := io.ReadFull(server, header); err != nil {
+			return
+		}
+		size := int32(binary.BigEndian.Uint32(header[0:4]))
+		body := make([]byte, size-8)
+		if _, err := io.ReadFull(server, body); err != nil {
+			return
+		}
+		packet := &RCONPacket{
+			Size: size,
+			ID:   int32(binary.BigEndian.Uint32(header[4:8])),
+			Type: int32(binary.BigEndian.Uint32(header[8:12])),
+			Body: string(body[:len(body)-2]),
+		}
+		received <- packet
+
+		reply := "Seed: [-4172144997902289642]"
+		buf := binary.BigEndian.AppendUint32(nil, uint32(len(reply)+packetOverhead))
+		buf = binary.BigEndian.AppendUint32(buf, uint32(packet.ID))
+		buf = binary.BigEndian.AppendUint32(buf, rconResponseValue)
+		server.Write(append(append(buf, reply...), 0, 0))
+	}()
+
+	body, err := client.Send("seed")
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if want := "Seed: [-4172144997902289642]"; body != want {
+		t.Errorf("response = %q, want %q", body, want)
+	}
+
+	want := &RCONPacket{Size: 14, ID: 0x01020304, Type: rconExecCommand, Body: "seed"}
+	if got := <-received; *got != *want {
+		t.Errorf("server received %+v, want %+v", *got, *want)
+	}
+}
+
+// runTerminal runs terminal mode on input and returns its exit status and
+// everything printed to standard output, including readline's output
+func runTerminal(t *testing.T, client *RCONClient, input string) (status int, stdout, stderr string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir()) // for the history file
+
+	stdin := readline.Stdin
+	readline.Stdin = io.NopCloser(strings.NewReader(input))
+	defer func() { readline.Stdin = stdin }()
+
+	stderr = captureStderr(t, func() {
+		stdout = captureStdout(t, func() { status = client.RunTerminalMode() })
+	})
+	return status, stdout, stderr
+}
+
+func TestTerminalModeQuiet(t *testing.T) {
+	client := newAuthenticatedClient(t, &Config{DisableColors: true}, rconHandler(echo))
+	_, stdout, stderr := runTerminal(t, client, "list\nq\n")
+	if !strings.Contains(stderr, "Logged in.") {
+		t.Errorf("terminal mode printed no banner to stderr by default:\n%q", stderr)
+	}
+	if stdout != "list\n" {
+		t.Errorf("terminal mode printed %q to stdout, want only the response", stdout)
+	}
+
+	client = newAuthenticatedClient(t, &Config{DisableColors: true, QuietAuth: true, NoPrompt: true}, rconHandler(echo))
+	status, stdout, stderr := runTerminal(t, client, "list\nseed\nq\n")
+	if status != 0 {
+		t.Errorf("RunTerminalMode returned %d, want 0", status)
+	}
+	if stdout != "list\nseed\n" || stderr != "" {
+		t.Errorf("terminal mode with QuietAuth and NoPrompt printed %q and %q to stderr, want only the responses", stdout, stderr)
+	}
+}
+
+func TestTerminalModeStopBreak(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		input  string
+		want   []string
+	}{
+		{"default", &Config{}, "stop\nlist\n", []string{"stop"}},
+		{"no stop break", &Config{NoStopBreak: true}, "stop\nlist\nq\n", []string{"stop", "list"}},
+		{"custom stop command", &Config{StopCommand: "end"}, "stop\nend\nlist\n", []string{"stop", "end"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commands := make(chan string, 4)
+			tt.config.QuietAuth, tt.config.NoPrompt = true, true
+			client := newAuthenticatedClient(t, tt.config, recordingHandler(commands))
+
+			runTerminal(t, client, tt.input)
+			close(commands)
+
+			var got []string
+			for command := range commands {
+				got = append(got, command)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("server received %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTerminalModeFileStdin(t *testing.T) {
+	client := newAuthenticatedClient(t, &Config{DisableColors: true}, rconHandler(echo))
+	t.Setenv("HOME", t.TempDir()) // for the history file
+
+	path := filepath.Join(t.TempDir(), "commands.txt")
+	if err := os.WriteFile(path, []byte("list\nseed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	stdin, rlStdin := os.Stdin, readline.Stdin
+	os.Stdin, readline.Stdin = f, f
+	defer func() { os.Stdin, readline.Stdin = stdin, rlStdin }()
+
+	var status int
+	out := captureStdout(t, func() {
+		stdout := readline.Stdout
+		readline.Stdout = os.Stdout
+		defer func() { readline.Stdout = stdout }()
+
+		status = client.RunTerminalMode()
+	})
+
+	if status != 0 {
+		t.Errorf("RunTerminalMode returned %d, want 0", status)
+	}
+	if want := "list\nseed\n"; out != want {
+		t.Errorf("stdout = %q, want only the responses %q", out, want)
+	}
+}
+
+func TestTerminalModePromptBeforeInput(t *testing.T) {
+	client := newAuthenticatedClient(t, &Config{QuietAuth: true}, rconHandler(echo))
+	t.Setenv("HOME", t.TempDir()) // for the history file
+
+	stdinR, stdinW := io.Pipe()
+	stdin := readline.Stdin
+	readline.Stdin = stdinR
+	defer func() { readline.Stdin = stdin }()
+
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = stderrW
+	defer func() { os.Stderr = stderr }()
+
+	done := make(chan int)
+	go func() { done <- client.RunTerminalMode() }()
+
+	// Nothing has been typed yet, so the prompt must already be out
+	stderrR.SetReadDeadline(time.Now().Add(5 * time.Second))
+	prompt := make([]byte, 2)
+	if _, err := io.ReadFull(stderrR, prompt); err != nil || string(prompt) != "> " {
+		t.Errorf("read %q, %v before any input, want the prompt", prompt, err)
+	}
+
+	stdinW.Write([]byte("q\n"))
+	go io.Copy(io.Discard, stderrR)
+	if status := <-done; status != 0 {
+		t.Errorf("RunTerminalMode returned %d, want 0", status)
+	}
+	stderrW.Close()
+}
+
+func TestAuthenticateWrongType(t *testing.T) {
+	client := newTestClient(t, &Config{Password: testPassword}, func(p *RCONPacket) []*RCONPacket {
+		return []*RCONPacket{{ID: p.ID, Type: 5, Body: "§cLogin not enabled"}}
+	})
+
+	err := client.Authenticate()
+	if err == nil {
+		t.Fatal("Authenticate accepted a response of the wrong type")
+	}
+	for _, want := range []string{"unexpected response type 5 (expected 2)", "Login not enabled"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package mcrcon
+
+import "time"
+
+// Clock tells the time and waits. The client uses it for the delays
+// between commands and between connection attempts, so that tests can
+// substitute a fake clock and check those delays without waiting.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the Clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
@@ -0,0 +1,114 @@
+package mcrcon
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock records the delays it is asked for and passes them at once by
+// advancing its own time. With block set, After calls beyond the first
+// fire never do.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+	block bool
+	fire  int
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Sleep(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.waits = append(f.waits, d)
+	f.now = f.now.Add(d)
+}
+
+func (f *fakeClock) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.waits = append(f.waits, d)
+	ch := make(chan time.Time, 1)
+	if !f.block || len(f.waits) <= f.fire {
+		f.now = f.now.Add(d)
+		ch <- f.now
+	}
+	return ch
+}
+
+// recorded returns the delays asked for so far
+func (f *fakeClock) recorded() []time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.waits)
+}
+
+func TestWaitBetweenCommands(t *testing.T) {
+	clock := &fakeClock{}
+	client := newAuthenticatedClient(t, &Config{SilentMode: true, Wait: 5 * time.Second, Clock: clock}, rconHandler(echo))
+
+	if code := client.RunCommands([]string{"list", "seed", "tps"}); code != 0 {
+		t.Errorf("RunCommands returned %d, want 0", code)
+	}
+	if got, want := clock.recorded(), []time.Duration{5 * time.Second, 5 * time.Second}; !slices.Equal(got, want) {
+		t.Errorf("waits = %v, want %v", got, want)
+	}
+}
+
+func TestWaitInterrupted(t *testing.T) {
+	clock := &fakeClock{block: true}
+	commands := make(chan string, 3)
+	client := newAuthenticatedClient(t, &Config{SilentMode: true, Wait: time.Hour, Clock: clock}, recordingHandler(commands))
+
+	var code int
+	stderr := captureStderr(t, func() {
+		done := make(chan int)
+		go func() { done <- client.RunCommands([]string{"list", "seed"}) }()
+
+		// Close the client once the first wait has started
+		for len(clock.recorded()) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		client.Close()
+		code = <-done
+	})
+
+	if code == 0 {
+		t.Error("RunCommands returned 0 after being interrupted")
+	}
+	if !strings.Contains(stderr, "Interrupted while waiting") {
+		t.Errorf("stderr = %q, want an interruption message", stderr)
+	}
+	if len(commands) != 1 {
+		t.Errorf("server received %d commands, want 1", len(commands))
+	}
+}
+
+func TestDialRetryPause(t *testing.T) {
+	// Nothing listens on the port once the listener is closed
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+
+	clock := &fakeClock{}
+	if _, err := dial(&Config{Host: host, Port: port, Clock: clock}); err == nil {
+		t.Fatal("dial succeeded without a listener")
+	}
+
+	want := slices.Repeat([]time.Duration{time.Second}, dialAttempts-1)
+	if got := clock.recorded(); !slices.Equal(got, want) {
+		t.Errorf("pauses = %v, want %v", got, want)
+	}
+}
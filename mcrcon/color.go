@@ -1,66 +1,208 @@
 package mcrcon
 
 import (
-    "strings"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
-// stripColorCodes removes Minecraft color codes
-func stripColorCodes(text string) string {
+// colorCodeAt returns the length of the color code starting at text[i]
+// and its code character, or 0 if there is none. Recognized forms are:
+//
+//   - the section sign as the two-byte UTF-8 sequence 0xc2 0xa7 followed
+//     by any code character, which is in range whenever i+2 < len(text),
+//     including at the very end of text
+//   - a bare 0xa7 byte, as sent by some Bedrock servers and proxies,
+//     followed by a valid code character
+//   - with ampersand set, '&' followed by a valid code character, as used
+//     by many plugins
+//
+// Callers step through text by runes, so a 0xa7 byte that belongs to
+// another character, such as 'ç' (0xc3 0xa7), is never looked at here.
+func colorCodeAt(text string, i int, ampersand bool) (int, byte) {
+	switch {
+	case i+2 < len(text) && text[i] == 0xc2 && text[i+1] == 0xa7:
+		return 3, text[i+2]
+	case i+1 < len(text) && text[i] == 0xa7 && isFormatCode(text[i+1]):
+		return 2, text[i+1]
+	case ampersand && i+1 < len(text) && text[i] == '&' && isFormatCode(text[i+1]):
+		return 2, text[i+1]
+	}
+	return 0, 0
+}
+
+// isFormatCode reports whether c is a Minecraft color or formatting code:
+// 0-9 and a-f for colors, k-o for formatting and r for reset
+func isFormatCode(c byte) bool {
+	c = lower(c)
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'k' && c <= 'o' || c == 'r'
+}
+
+// lower returns the lowercase form of an ASCII letter
+func lower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// stripColorCodes removes Minecraft color codes, including &-codes if
+// ampersand is set
+func stripColorCodes(text string, ampersand bool) string {
 	var result strings.Builder
 	result.Grow(len(text))
 
-	for i := 0; i < len(text); i++ {
-		if i+2 < len(text) && text[i] == 0xc2 && text[i+1] == 0xa7 {
-			i += 2 // Skip color code
+	for i := 0; i < len(text); {
+		if n, _ := colorCodeAt(text, i, ampersand); n > 0 {
+			i += n // Skip color code
 			continue
 		}
-		result.WriteByte(text[i])
+		_, size := utf8.DecodeRuneInString(text[i:])
+		result.WriteString(text[i : i+size])
+		i += size
 	}
 
 	return result.String()
 }
 
-// convertColorCodes converts Minecraft color codes to ANSI
-func convertColorCodes(text string) string {
-	colorMap := map[byte]string{
-		'0': "\033[0;30m",   // BLACK
-		'1': "\033[0;34m",   // BLUE
-		'2': "\033[0;32m",   // GREEN
-		'3': "\033[0;36m",   // CYAN
-		'4': "\033[0;31m",   // RED
-		'5': "\033[0;35m",   // PURPLE
-		'6': "\033[0;33m",   // GOLD
-		'7': "\033[0;37m",   // GREY
-		'8': "\033[0;1;30m", // DGREY
-		'9': "\033[0;1;34m", // LBLUE
-		'a': "\033[0;1;32m", // LGREEN
-		'b': "\033[0;1;36m", // LCYAN
-		'c': "\033[0;1;31m", // LRED
-		'd': "\033[0;1;35m", // LPURPLE
-		'e': "\033[0;1;33m", // YELLOW
-		'f': "\033[0;1;37m", // WHITE
-		'n': "\033[4m",      // UNDERLINE
-		'r': "\033[0m",      // RESET
-	}
+// defaultColorMap maps Minecraft color codes to ANSI escape sequences
+var defaultColorMap = map[byte]string{
+	'0': "\033[0;30m",   // BLACK
+	'1': "\033[0;34m",   // BLUE
+	'2': "\033[0;32m",   // GREEN
+	'3': "\033[0;36m",   // CYAN
+	'4': "\033[0;31m",   // RED
+	'5': "\033[0;35m",   // PURPLE
+	'6': "\033[0;33m",   // GOLD
+	'7': "\033[0;37m",   // GREY
+	'8': "\033[0;1;30m", // DGREY
+	'9': "\033[0;1;34m", // LBLUE
+	'a': "\033[0;1;32m", // LGREEN
+	'b': "\033[0;1;36m", // LCYAN
+	'c': "\033[0;1;31m", // LRED
+	'd': "\033[0;1;35m", // LPURPLE
+	'e': "\033[0;1;33m", // YELLOW
+	'f': "\033[0;1;37m", // WHITE
+	'n': "\033[4m",      // UNDERLINE
+	'r': "\033[0m",      // RESET
+}
 
+// convertColorCodes converts Minecraft color codes, including &-codes if
+// ampersand is set, to ANSI. Codes found in overrides take precedence over
+// the default mapping.
+func convertColorCodes(text string, overrides map[byte]string, ampersand bool) string {
 	var result strings.Builder
 	result.Grow(len(text))
 
-	for i := 0; i < len(text); i++ {
-		if i+2 < len(text) && text[i] == 0xc2 && text[i+1] == 0xa7 {
-			colorCode := text[i+2]
-			if ansi, ok := colorMap[colorCode]; ok {
+	for i := 0; i < len(text); {
+		if n, colorCode := colorCodeAt(text, i, ampersand); n > 0 {
+			colorCode = lower(colorCode)
+			if ansi, ok := overrides[colorCode]; ok {
+				result.WriteString(ansi)
+			} else if ansi, ok := defaultColorMap[colorCode]; ok {
 				result.WriteString(ansi)
 			}
-			i += 2
+			i += n
 			continue
 		}
 		if text[i] == '\n' {
 			result.WriteString("\033[0m")
 		}
-		result.WriteByte(text[i])
+		_, size := utf8.DecodeRuneInString(text[i:])
+		result.WriteString(text[i : i+size])
+		i += size
 	}
 
 	result.WriteString("\033[0m") // Reset color at end
 	return result.String()
 }
+
+// colorTagNames maps Minecraft color and formatting codes to the names
+// used by tagColorCodes, which are the game's own
+var colorTagNames = map[byte]string{
+	'0': "black",
+	'1': "dark_blue",
+	'2': "dark_green",
+	'3': "dark_aqua",
+	'4': "dark_red",
+	'5': "dark_purple",
+	'6': "gold",
+	'7': "gray",
+	'8': "dark_gray",
+	'9': "blue",
+	'a': "green",
+	'b': "aqua",
+	'c': "red",
+	'd': "light_purple",
+	'e': "yellow",
+	'f': "white",
+	'k': "obfuscated",
+	'l': "bold",
+	'm': "strikethrough",
+	'n': "underline",
+	'o': "italic",
+}
+
+// tagColorCodes renders Minecraft color codes, including &-codes if
+// ampersand is set, as readable tags like [red]text[/red] for logs. As
+// in game, a color ends the open color and formatting, formatting adds
+// to them and a reset ends everything; tags still open at the end are
+// closed.
+func tagColorCodes(text string, ampersand bool) string {
+	var result strings.Builder
+	result.Grow(len(text))
+
+	var open []string
+	closeTags := func() {
+		for i := len(open) - 1; i >= 0; i-- {
+			result.WriteString("[/" + open[i] + "]")
+		}
+		open = open[:0]
+	}
+
+	for i := 0; i < len(text); {
+		if n, colorCode := colorCodeAt(text, i, ampersand); n > 0 {
+			colorCode = lower(colorCode)
+			name, ok := colorTagNames[colorCode]
+			if colorCode == 'r' || ok && colorCode < 'k' {
+				closeTags()
+			}
+			if ok {
+				result.WriteString("[" + name + "]")
+				open = append(open, name)
+			}
+			i += n
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		result.WriteString(text[i : i+size])
+		i += size
+	}
+
+	closeTags()
+	return result.String()
+}
+
+// ParseColorMap parses comma-separated code=sequence pairs such as
+// "7=\033[0;90m,e=\e[1;33m" into a color map for Config.ColorMap.
+// Escapes like \033, \x1b and \e in sequences are decoded.
+func ParseColorMap(spec string) (map[byte]string, error) {
+	colorMap := make(map[byte]string)
+
+	for _, pair := range strings.Split(spec, ",") {
+		code, seq, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || len(code) != 1 {
+			return nil, fmt.Errorf("invalid color mapping %q: expected code=sequence", pair)
+		}
+
+		ansi, err := strconv.Unquote(`"` + strings.ReplaceAll(seq, `\e`, `\x1b`) + `"`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid escape sequence in color mapping %q", pair)
+		}
+
+		colorMap[strings.ToLower(code)[0]] = ansi
+	}
+
+	return colorMap, nil
+}
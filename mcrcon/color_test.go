@@ -0,0 +1,100 @@
+package mcrcon
+
+import "testing"
+
+func TestStripColorCodes(t *testing.T) {
+	tests := []struct {
+		text      string
+		ampersand bool
+		want      string
+	}{
+		{"§aHello §bworld", false, "Hello world"},
+		{"Hello§a", false, "Hello"}, // code at the very end
+		{"§", false, "§"},
+		{"Hello\xa7c", false, "Hello"},
+		{"façade", false, "façade"}, // 'ç' ends in 0xa7 but isn't a code
+		{"&cred &zfoo", true, "red &zfoo"},
+		{"&cred", false, "&cred"},
+	}
+
+	for _, tt := range tests {
+		if got := stripColorCodes(tt.text, tt.ampersand); got != tt.want {
+			t.Errorf("stripColorCodes(%q, %v) = %q, want %q", tt.text, tt.ampersand, got, tt.want)
+		}
+	}
+}
+
+func TestConvertColorCodes(t *testing.T) {
+	const reset = "\033[0m"
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"§cError", defaultColorMap['c'] + "Error" + reset},
+		{"Done§a", "Done" + defaultColorMap['a'] + reset}, // code at the very end
+		{"§Cupper", defaultColorMap['c'] + "upper" + reset},
+		{"a\nb", "a" + reset + "\nb" + reset},
+	}
+
+	for _, tt := range tests {
+		if got := convertColorCodes(tt.text, nil, false); got != tt.want {
+			t.Errorf("convertColorCodes(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTagColorCodes(t *testing.T) {
+	tests := []struct {
+		text      string
+		ampersand bool
+		want      string
+	}{
+		{"§cError", false, "[red]Error[/red]"},
+		{"plain", false, "plain"},
+		{"§aok §cfail", false, "[green]ok [/green][red]fail[/red]"},             // a color closes the open one
+		{"§6§lgold bold", false, "[gold][bold]gold bold[/bold][/gold]"},         // formatting nests
+		{"§l§obold italic", false, "[bold][italic]bold italic[/italic][/bold]"}, // formatting adds up
+		{"§cred§r plain", false, "[red]red[/red] plain"},                        // a reset closes everything
+		{"§Cupper", false, "[red]upper[/red]"},
+		{"§zbad", false, "bad"}, // unknown codes are dropped
+		{"&eyellow", true, "[yellow]yellow[/yellow]"},
+		{"&eyellow", false, "&eyellow"},
+	}
+
+	for _, tt := range tests {
+		if got := tagColorCodes(tt.text, tt.ampersand); got != tt.want {
+			t.Errorf("tagColorCodes(%q, %v) = %q, want %q", tt.text, tt.ampersand, got, tt.want)
+		}
+	}
+}
+
+func TestConvertColorCodesOverrides(t *testing.T) {
+	overrides, err := ParseColorMap(`7=\033[0;90m, A=\e[1m`)
+	if err != nil {
+		t.Fatalf("ParseColorMap: %v", err)
+	}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"§7grey", "\033[0;90mgrey\033[0m"},                 // overridden
+		{"§abold", "\033[1mbold\033[0m"},                    // overridden, code given in upper case
+		{"§cred", defaultColorMap['c'] + "red" + "\033[0m"}, // passed through to the default
+	}
+
+	for _, tt := range tests {
+		if got := convertColorCodes(tt.text, overrides, false); got != tt.want {
+			t.Errorf("convertColorCodes(%q) with overrides = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseColorMapErrors(t *testing.T) {
+	for _, spec := range []string{`7`, `77=\033[0m`, `7=\q`} {
+		if _, err := ParseColorMap(spec); err == nil {
+			t.Errorf("ParseColorMap(%q) succeeded, want an error", spec)
+		}
+	}
+}
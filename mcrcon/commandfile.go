@@ -0,0 +1,69 @@
+package mcrcon
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReadCommandFile reads rcon commands from a script file, one per line.
+// Blank lines and lines starting with '#' are ignored, and a line ending
+// with a backslash continues on the next line, so long commands such as
+// tellraw JSON can span several lines.
+func ReadCommandFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open command file: %w", err)
+	}
+	defer f.Close()
+
+	commands, err := readCommands(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read command file %s: %w", path, err)
+	}
+
+	return commands, nil
+}
+
+// readCommands collects commands from r, skipping blank lines and comments
+// and joining continued lines with a space
+func readCommands(r io.Reader) ([]string, error) {
+	var commands []string
+
+	var pending []string // lines of a command continued with a backslash
+	start := 0           // line number where the pending command started
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(pending) == 0 && (len(line) == 0 || strings.HasPrefix(line, "#")) {
+			continue
+		}
+		if len(pending) == 0 {
+			start = n
+		}
+
+		if continued, ok := strings.CutSuffix(line, "\\"); ok {
+			pending = append(pending, strings.TrimSpace(continued))
+			continue
+		}
+
+		command := strings.Join(append(pending, line), " ")
+		pending = nil
+		if len(command) > maxCommandSize {
+			return nil, fmt.Errorf("line %d: command too long (%d bytes). Maximum: %d bytes", start, len(command), maxCommandSize)
+		}
+		commands = append(commands, command)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(pending) > 0 {
+		return nil, fmt.Errorf("line %d: command continues past the end of the file", start)
+	}
+
+	return commands, nil
+}
@@ -0,0 +1,48 @@
+package mcrcon
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadCommandsContinuation(t *testing.T) {
+	const script = `# announce the restart
+tellraw @a {"text": \
+    "Restarting soon", \
+    "color": "red"}
+
+save-all
+`
+	commands, err := readCommands(strings.NewReader(script))
+	if err != nil {
+		t.Fatalf("readCommands: %v", err)
+	}
+
+	want := []string{`tellraw @a {"text": "Restarting soon", "color": "red"}`, "save-all"}
+	if !slices.Equal(commands, want) {
+		t.Errorf("commands = %q, want %q", commands, want)
+	}
+}
+
+func TestReadCommandsErrors(t *testing.T) {
+	// Each line fits, but the joined command doesn't
+	half := strings.Repeat("a", maxCommandSize/2+1)
+	tooLong := "list\nsay " + half + " \\\n" + half + "\n"
+
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"joined command too long", tooLong, "line 2: command too long"},
+		{"continuation at end of file", "list\nsay hi \\\n", "line 2: command continues past the end of the file"},
+	}
+
+	for _, tt := range tests {
+		_, err := readCommands(strings.NewReader(tt.script))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: readCommands returned %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
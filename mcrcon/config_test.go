@@ -0,0 +1,235 @@
+package mcrcon
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConfigAddress(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"localhost", "localhost:25575"},
+		{"192.0.2.1", "192.0.2.1:25575"},
+		{"::1", "[::1]:25575"},
+		{"[::1]", "[::1]:25575"},
+		{"fe80::1%eth0", "[fe80::1%eth0]:25575"},
+		{"[fe80::1%eth0]", "[fe80::1%eth0]:25575"},
+	}
+
+	for _, tt := range tests {
+		config := &Config{Host: tt.host, Port: "25575"}
+		if got := config.address(); got != tt.want {
+			t.Errorf("address for host %q = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePort(t *testing.T) {
+	tests := []struct {
+		port  string
+		valid bool
+	}{
+		{"25575", true},
+		{"1", true},
+		{"65535", true},
+		{"", false},
+		{"notaport", false},
+		{"25575x", false},
+		{"0", false},
+		{"-1", false},
+		{"65536", false},
+	}
+
+	for _, tt := range tests {
+		config := &Config{Host: "localhost", Port: tt.port}
+		if err := config.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate with port %q returned %v, want valid = %v", tt.port, err, tt.valid)
+		}
+	}
+}
+
+func TestValidateRequestID(t *testing.T) {
+	config := &Config{Host: "localhost", Port: DefaultPort, RequestID: -1}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate accepted a negative request ID")
+	}
+}
+
+func TestValidateMaxCommands(t *testing.T) {
+	config := &Config{Host: "localhost", Port: DefaultPort, MaxCommands: -1}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate accepted a negative command limit")
+	}
+}
+
+func TestConfigWait(t *testing.T) {
+	tests := []struct {
+		wait, jitter time.Duration
+		min, max     time.Duration
+	}{
+		{2 * time.Second, 0, 2 * time.Second, 2 * time.Second},
+		{2 * time.Second, 500 * time.Millisecond, 1500 * time.Millisecond, 2500 * time.Millisecond},
+		{100 * time.Millisecond, time.Second, 0, 1100 * time.Millisecond},
+		{0, time.Second, 0, time.Second},
+	}
+
+	for _, tt := range tests {
+		config := &Config{Wait: tt.wait, Jitter: tt.jitter}
+		for range 1000 {
+			if got := config.wait(); got < tt.min || got > tt.max {
+				t.Fatalf("Wait %v with Jitter %v gave %v, want between %v and %v", tt.wait, tt.jitter, got, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+// listenTLS serves handle over TLS with a self-signed certificate for
+// 127.0.0.1, returning the listener's host and port and a pool that
+// trusts the certificate
+func listenTLS(t *testing.T, handle handlerFunc) (string, string, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port := serveListener(t, ln, handle, nil)
+	return host, port, roots
+}
+
+func TestTLS(t *testing.T) {
+	host, port, roots := listenTLS(t, rconHandler(echo))
+
+	tests := []struct {
+		name   string
+		config *tls.Config
+		ok     bool
+	}{
+		{"trusted CA", &tls.Config{RootCAs: roots}, true},
+		{"insecure", &tls.Config{InsecureSkipVerify: true}, true},
+		{"unknown CA", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewRCONClient(&Config{
+				Host: host, Port: port, Password: testPassword,
+				TLS: true, TLSConfig: tt.config, NoRetry: true,
+			})
+			if !tt.ok {
+				if err == nil {
+					client.Close()
+					t.Fatal("NewRCONClient succeeded without a trusted certificate")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewRCONClient: %v", err)
+			}
+			defer client.Close()
+
+			if err := client.Authenticate(); err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if body, err := client.Send("list"); err != nil || body != "list" {
+				t.Errorf("Send = %q, %v, want %q", body, err, "list")
+			}
+		})
+	}
+}
+
+func TestSourceAddr(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted := make(chan net.Conn, 1)
+	host, port := serveListener(t, ln, rconHandler(echo), accepted)
+
+	// Linux routes all of 127.0.0.0/8 to the loopback interface
+	client, err := NewRCONClient(&Config{Host: host, Port: port, SourceAddr: "127.0.0.2", NoRetry: true})
+	if err != nil {
+		t.Skipf("can't connect from 127.0.0.2: %v", err)
+	}
+	defer client.Close()
+
+	conn := <-accepted
+	if ip := conn.RemoteAddr().(*net.TCPAddr).IP; !ip.Equal(net.IPv4(127, 0, 0, 2)) {
+		t.Errorf("server saw a connection from %s, want 127.0.0.2", ip)
+	}
+}
+
+func TestInvalidSourceAddr(t *testing.T) {
+	for _, addr := range []string{"localhost", "127.0.0.1:http", "127.0.0.1:70000"} {
+		config := &Config{Host: "localhost", Port: DefaultPort, SourceAddr: addr}
+		if err := config.Validate(); err == nil {
+			t.Errorf("Validate accepted source address %q", addr)
+		}
+	}
+}
+
+func TestPrepareCommand(t *testing.T) {
+	config := &Config{
+		CommandPrefix: "execute as ${player} run",
+		Vars:          map[string]string{"player": "Steve", "cmd": "stop"},
+		Deny:          commandPatterns(t, "stop"),
+	}
+
+	tests := []struct {
+		command string
+		want    string
+		denied  bool
+	}{
+		{command: "say hi", want: "execute as Steve run say hi"},
+		{command: "say $$5", want: "execute as Steve run say $5"},
+		{command: "stop", denied: true}, // checked before the prefix is added
+		{command: "/STOP", denied: true},
+		{command: "${cmd}", denied: true},
+	}
+
+	for _, tt := range tests {
+		got, err := config.prepareCommand(tt.command)
+		if tt.denied {
+			if !errors.Is(err, ErrCommandDenied) {
+				t.Errorf("prepareCommand(%q) = %q, %v, want ErrCommandDenied", tt.command, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("prepareCommand(%q) = %q, %v, want %q", tt.command, got, err, tt.want)
+		}
+	}
+}
@@ -1,11 +1,67 @@
 package mcrcon
 
+import (
+	"time"
+)
+
 const (
-	Version        = "0.1.0"
-	AppName        = "mcrcon-go"
-	DefaultPort    = "25575"
-	DefaultHost    = "localhost"
-	MaxWaitTime    = 600
-	dataBuffSize   = 4096
-	rconPID        = 0xBADC0DE
+	Version      = "0.1.0"
+	AppName      = "mcrcon-go"
+	DefaultPort  = "25575"
+	DefaultHost  = "localhost"
+	dataBuffSize = 4096
+	rconPID      = 0xBADC0DE
+
+	// DefaultEmptyMarker is what terminal mode prints for an empty response
+	DefaultEmptyMarker = "(no output)"
+
+	// maxCommandSize is the largest command body in bytes that keeps the
+	// packet, including its overhead, within the server's buffer
+	maxCommandSize = dataBuffSize - packetOverhead - 1
+
+	// maxResponseSize is the largest Size accepted from the server. The
+	// 4096 byte buffer only limits what the server reads; responses carry
+	// up to 4096 bytes of body on top of the packet overhead.
+	maxResponseSize = dataBuffSize + packetOverhead
+
+	// defaultTimeout limits connecting and waiting for a response
+	defaultTimeout = 10 * time.Second
+
+	// defaultFollowWindow is how long terminal mode waits for additional
+	// output after a response when following
+	defaultFollowWindow = 200 * time.Millisecond
+
+	// defaultStopCommand ends terminal mode sessions once sent, since the
+	// server shuts down in response
+	defaultStopCommand = "stop"
+
+	// dialAttempts is how many times to try connecting before giving up
+	dialAttempts = 3
+
+	// truncatedMarker is appended to responses cut short by MaxBodyBytes
+	truncatedMarker = "...[truncated]"
+
+	// drainWindow is how long to wait for stray data when draining the
+	// connection before a command
+	drainWindow = 10 * time.Millisecond
+
+	// maxReconnectBackoff caps the delay between attempts when waiting for
+	// a server to come back
+	maxReconnectBackoff = 10 * time.Second
+
+	// defaultKeepAlive is the TCP keep-alive period for idle connections
+	defaultKeepAlive = 30 * time.Second
+
+	// maxQueuedCommands bounds the commands QueueCommand holds while
+	// disconnected
+	maxQueuedCommands = 100
+
+	// failureStatusBase is added to the failure count returned as the exit
+	// status with KeepGoing, so it can't be mistaken for 1 (other errors)
+	// or 2 (rejected password)
+	failureStatusBase = 10
+
+	// maxFailureStatus caps the exit status with KeepGoing, staying clear
+	// of the codes shells reserve
+	maxFailureStatus = 125
 )
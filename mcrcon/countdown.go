@@ -0,0 +1,71 @@
+package mcrcon
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// countdownStep is an announcement and the wait that follows it
+type countdownStep struct {
+	remaining time.Duration // time left when the announcement is made
+	wait      time.Duration // time until the next announcement or the final commands
+}
+
+// countdownSteps orders the announcement offsets from the furthest to the
+// nearest, dropping duplicates and non-positive offsets, and works out
+// how long to wait after each one
+func countdownSteps(offsets []time.Duration) []countdownStep {
+	sorted := slices.DeleteFunc(slices.Clone(offsets), func(d time.Duration) bool { return d <= 0 })
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+	slices.Reverse(sorted)
+
+	steps := make([]countdownStep, len(sorted))
+	for i, remaining := range sorted {
+		next := time.Duration(0)
+		if i+1 < len(sorted) {
+			next = sorted[i+1]
+		}
+		steps[i] = countdownStep{remaining: remaining, wait: remaining - next}
+	}
+	return steps
+}
+
+// RunCountdown announces with "say" how long remains at each of the given
+// offsets before running commands, e.g. "Restart in %d seconds" at 60s,
+// 30s, 10s and 5s before a stop. The first announcement is made right
+// away and "%d" in message is replaced by the whole seconds remaining.
+// Failures are handled as in RunCommands.
+func (c *RCONClient) RunCountdown(message string, offsets []time.Duration, commands []string) int {
+	if err := c.config.checkCommandCount(len(commands)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	for _, step := range countdownSteps(offsets) {
+		text := strings.ReplaceAll(message, "%d", strconv.Itoa(int(step.remaining.Seconds())))
+		if err := c.ExecuteCommand("say " + text); err != nil {
+			fmt.Fprintf(os.Stderr, "Command failed: %v\n", err)
+			if !c.config.KeepGoing {
+				return 1
+			}
+		}
+
+		if c.config.DryRun {
+			fmt.Printf("(wait %s)\n", step.wait)
+			continue
+		}
+		select {
+		case <-c.config.clock().After(step.wait):
+		case <-c.closed:
+			fmt.Fprintln(os.Stderr, "Interrupted while waiting")
+			return 1
+		}
+	}
+
+	return c.RunCommands(commands)
+}
@@ -0,0 +1,32 @@
+package mcrcon
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCountdownSteps(t *testing.T) {
+	s := time.Second
+	tests := []struct {
+		offsets []time.Duration
+		want    []countdownStep
+	}{
+		{
+			offsets: []time.Duration{60 * s, 30 * s, 10 * s, 5 * s},
+			want:    []countdownStep{{60 * s, 30 * s}, {30 * s, 20 * s}, {10 * s, 5 * s}, {5 * s, 5 * s}},
+		},
+		{
+			// Unordered, duplicated and non-positive offsets
+			offsets: []time.Duration{10 * s, 0, 30 * s, 10 * s, -5 * s},
+			want:    []countdownStep{{30 * s, 20 * s}, {10 * s, 10 * s}},
+		},
+		{offsets: nil, want: []countdownStep{}},
+	}
+
+	for _, tt := range tests {
+		if got := countdownSteps(tt.offsets); !slices.Equal(got, tt.want) {
+			t.Errorf("countdownSteps(%v) = %v, want %v", tt.offsets, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package mcrcon
+
+import (
+	"fmt"
+	"time"
+)
+
+// Dial connects to the server at host and port and authenticates with
+// password, returning a client ready for Send. Errors wrap ErrAuthFailed
+// when the password is rejected. Use NewRCONClient for other settings.
+func Dial(host, port, password string) (*RCONClient, error) {
+	client, err := NewRCONClient(&Config{
+		Host:     host,
+		Port:     port,
+		Password: password,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Authenticate(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// Exec connects, authenticates, sends a single command and disconnects,
+// returning the response body. It suits one-off commands; reuse a client
+// from Dial to send several.
+func Exec(host, port, password, command string) (string, error) {
+	client, err := Dial(host, port, password)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	return client.Send(command)
+}
+
+// WaitForServer connects and authenticates like NewRCONClient followed
+// by Authenticate, but keeps trying with backoff until the server accepts
+// or timeout passes, for scripts that run right after starting a server.
+// A rejected password ends the wait at once with ErrAuthFailed.
+func WaitForServer(config *Config, timeout time.Duration) (*RCONClient, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	client := NewClientWithConn(nil, config)
+	if config.DryRun {
+		return client, nil
+	}
+
+	if err := client.waitForReconnect(timeout); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// Run sends commands in order, authenticating first unless the client
+// already has, and returns their responses. It stops at the first
+// failed command, returning the responses so far with an error naming
+// the command. Commands get the prefix and variables configured for
+// ExecuteCommand, but nothing is printed and the client stays open.
+func (c *RCONClient) Run(commands []string) ([]string, error) {
+	c.mu.Lock()
+	authenticated := c.authenticated
+	c.mu.Unlock()
+
+	if !authenticated {
+		if err := c.Authenticate(); err != nil {
+			return nil, err
+		}
+	}
+
+	responses := make([]string, 0, len(commands))
+	for _, command := range commands {
+		prepared, err := c.config.prepareCommand(command)
+		if err != nil {
+			return responses, fmt.Errorf("%q: %w", command, err)
+		}
+
+		body, err := c.sendReconnecting(prepared)
+		if err != nil {
+			return responses, fmt.Errorf("%q: %w", command, err)
+		}
+		responses = append(responses, body)
+	}
+	return responses, nil
+}
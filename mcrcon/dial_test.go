@@ -0,0 +1,202 @@
+package mcrcon
+
+import (
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDial(t *testing.T) {
+	host, port := listen(t, rconHandler(echo))
+
+	client, err := Dial(host, port, testPassword)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer client.Close()
+
+	if body, err := client.Send("list"); err != nil || body != "list" {
+		t.Errorf("Send = %q, %v, want %q", body, err, "list")
+	}
+}
+
+func TestDialWrongPassword(t *testing.T) {
+	host, port := listen(t, rconHandler(echo))
+
+	if _, err := Dial(host, port, "wrong"); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Dial returned %v, want ErrAuthFailed", err)
+	}
+}
+
+func TestExec(t *testing.T) {
+	host, port := listen(t, rconHandler(func(command string) string {
+		return "There are 0 of a max of 20 players online: "
+	}))
+
+	body, err := Exec(host, port, testPassword, "list")
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if want := "There are 0 of a max of 20 players online: "; body != want {
+		t.Errorf("Exec = %q, want %q", body, want)
+	}
+
+	if _, err := Exec(host, port, "wrong", "list"); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Exec with a wrong password returned %v, want ErrAuthFailed", err)
+	}
+}
+
+// countingAuths wraps handle, counting the authentication packets it
+// receives and sending the commands to commands
+func countingAuths(auths *atomic.Int32, commands chan<- string, handle handlerFunc) handlerFunc {
+	return func(p *RCONPacket) []*RCONPacket {
+		if p.Type == rconAuthenticate {
+			auths.Add(1)
+		} else {
+			commands <- p.Body
+		}
+		return handle(p)
+	}
+}
+
+func TestRun(t *testing.T) {
+	var auths atomic.Int32
+	commands := make(chan string, 10)
+	reply := rconHandler(func(command string) string { return "re: " + command })
+	client := newPipeClient(t, &Config{Password: testPassword, CommandPrefix: "execute run"}, countingAuths(&auths, commands, reply))
+
+	responses, err := client.Run([]string{"list", "seed"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if want := []string{"re: execute run list", "re: execute run seed"}; !slices.Equal(responses, want) {
+		t.Errorf("Run returned %q, want %q", responses, want)
+	}
+
+	// The second batch reuses the authenticated connection
+	if _, err := client.Run([]string{"time query day"}); err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	if n := auths.Load(); n != 1 {
+		t.Errorf("authenticated %d times for two batches, want 1", n)
+	}
+}
+
+func TestRunStopsAtFailure(t *testing.T) {
+	var auths atomic.Int32
+	commands := make(chan string, 10)
+	config := &Config{Password: testPassword, Deny: commandPatterns(t, "stop")}
+	client := newPipeClient(t, config, countingAuths(&auths, commands, rconHandler(echo)))
+
+	responses, err := client.Run([]string{"list", "stop", "seed"})
+	if !errors.Is(err, ErrCommandDenied) {
+		t.Errorf("Run returned %v, want ErrCommandDenied", err)
+	}
+	if want := []string{"list"}; !slices.Equal(responses, want) {
+		t.Errorf("Run returned %q, want %q", responses, want)
+	}
+	close(commands)
+	var sent []string
+	for command := range commands {
+		sent = append(sent, command)
+	}
+	if want := []string{"list"}; !slices.Equal(sent, want) {
+		t.Errorf("server received %q, want %q", sent, want)
+	}
+}
+
+func TestRunAfterExpiredSession(t *testing.T) {
+	// The first command is refused as if the session had expired
+	var auths, refused atomic.Int32
+	commands := make(chan string, 10)
+	handle := rconHandler(echo)
+	client := newPipeClient(t, &Config{Password: testPassword}, countingAuths(&auths, commands, func(p *RCONPacket) []*RCONPacket {
+		if p.Type == rconExecCommand && refused.Add(1) == 1 {
+			return []*RCONPacket{{ID: -1, Type: rconResponseValue}}
+		}
+		return handle(p)
+	}))
+
+	if _, err := client.Run([]string{"list"}); !errors.Is(err, ErrReauthRequired) {
+		t.Fatalf("Run returned %v, want ErrReauthRequired", err)
+	}
+	if responses, err := client.Run([]string{"list"}); err != nil || !slices.Equal(responses, []string{"list"}) {
+		t.Errorf("Run after the expired session = %q, %v, want the response", responses, err)
+	}
+	if n := auths.Load(); n != 2 {
+		t.Errorf("authenticated %d times, want 2", n)
+	}
+}
+
+// startingClock is a fake clock that runs start when the client first
+// backs off, as if the server came up while it waited
+type startingClock struct {
+	*fakeClock
+	once  sync.Once
+	start func()
+}
+
+func (c *startingClock) After(d time.Duration) <-chan time.Time {
+	c.once.Do(c.start)
+	return c.fakeClock.After(d)
+}
+
+func TestWaitForServer(t *testing.T) {
+	// Nothing listens on the port until the first backoff
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	host, port, _ := net.SplitHostPort(address)
+	ln.Close()
+
+	clock := &startingClock{fakeClock: &fakeClock{}, start: func() {
+		ln, err := net.Listen("tcp", address)
+		if err != nil {
+			t.Fatalf("listening again on %s: %v", address, err)
+		}
+		serveListener(t, ln, rconHandler(echo), nil)
+	}}
+
+	var client *RCONClient
+	captureStderr(t, func() {
+		client, err = WaitForServer(&Config{Host: host, Port: port, Password: testPassword, Clock: clock}, time.Minute)
+	})
+	if err != nil {
+		t.Fatalf("WaitForServer: %v", err)
+	}
+	defer client.Close()
+
+	if body, err := client.Send("list"); err != nil || body != "list" {
+		t.Errorf("Send = %q, %v, want the response", body, err)
+	}
+}
+
+func TestWaitForServerTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+
+	config := &Config{Host: host, Port: port, Password: testPassword, Clock: &fakeClock{}}
+	if _, err := WaitForServer(config, 10*time.Second); err == nil || !strings.Contains(err.Error(), "server not available within 10s") {
+		t.Errorf("WaitForServer returned %v, want a timeout", err)
+	}
+}
+
+func TestWaitForServerWrongPassword(t *testing.T) {
+	host, port := listen(t, rconHandler(echo))
+
+	config := &Config{Host: host, Port: port, Password: "wrong", Clock: &fakeClock{}}
+	if _, err := WaitForServer(config, time.Minute); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("WaitForServer returned %v, want ErrAuthFailed", err)
+	}
+}
@@ -0,0 +1,33 @@
+// Package mcrcon implements a client for the Minecraft RCON protocol.
+//
+// A client keeps a single connection open, so one authenticated session
+// can be reused for any number of commands:
+//
+//	client, err := mcrcon.NewRCONClient(&mcrcon.Config{
+//		Host:     "localhost",
+//		Port:     mcrcon.DefaultPort,
+//		Password: "secret",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer client.Close()
+//
+//	if err := client.Authenticate(); err != nil {
+//		return err
+//	}
+//
+//	for _, command := range []string{"save-all", "list"} {
+//		response, err := client.Send(command)
+//		...
+//	}
+//
+// Dial does the same in a single call, and Exec runs a single command on
+// a fresh connection:
+//
+//	response, err := mcrcon.Exec("localhost", mcrcon.DefaultPort, "secret", "list")
+//
+// Connections are kept alive with TCP keep-alive (see Config.KeepAlive).
+// If the connection is lost anyway, Reauthenticate re-dials and logs in
+// again so the same client can carry on.
+package mcrcon
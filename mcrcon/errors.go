@@ -0,0 +1,66 @@
+package mcrcon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+)
+
+var (
+	// ErrAuthFailed is returned when the server rejects the rcon password
+	ErrAuthFailed = errors.New("authentication rejected")
+
+	// ErrConnClosed is returned when the connection was closed or reset
+	ErrConnClosed = errors.New("connection closed")
+
+	// ErrNotConnected is returned when sending or receiving before Connect,
+	// such as on a client created with New
+	ErrNotConnected = errors.New("not connected; call Connect first")
+
+	// ErrClosedAfterSend is returned when the server closed the connection
+	// after a command was sent but before responding, as it does on stop.
+	// It always comes together with ErrConnClosed.
+	ErrClosedAfterSend = errors.New("server closed the connection after the command was sent")
+
+	// ErrReauthRequired is returned when the server answers a command with
+	// ID -1, meaning the session is no longer authenticated, as some
+	// servers do after a period of inactivity
+	ErrReauthRequired = errors.New("server requires authentication again")
+
+	// ErrInfoUnavailable is returned by ServerInfo when the server doesn't
+	// report its version
+	ErrInfoUnavailable = errors.New("server does not report its version")
+
+	// ErrCommandDenied is returned for commands blocked by the Allow and
+	// Deny patterns, which are never sent
+	ErrCommandDenied = errors.New("command denied")
+
+	// ErrQueueFull is returned by QueueCommand when maxQueuedCommands
+	// commands are already waiting
+	ErrQueueFull = errors.New("command queue is full")
+)
+
+// wrapConnError tags errors caused by a closed connection with ErrConnClosed
+func wrapConnError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) {
+		return fmt.Errorf("%w: %w", ErrConnClosed, err)
+	}
+
+	return err
+}
+
+// isTimeout reports whether err is a network timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,32 @@
+package mcrcon_test
+
+import (
+	"fmt"
+	"log"
+
+	"mcrcon-go/mcrcon"
+)
+
+func ExampleExec() {
+	response, err := mcrcon.Exec("localhost", mcrcon.DefaultPort, "secret", "list")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(response)
+}
+
+func ExampleDial() {
+	client, err := mcrcon.Dial("localhost", mcrcon.DefaultPort, "secret")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	for _, command := range []string{"save-all", "list"} {
+		response, err := client.Send(command)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(response)
+	}
+}
@@ -0,0 +1,48 @@
+package mcrcon
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// FormatData is passed to the Config.Format template for each command
+type FormatData struct {
+	Command   string
+	Response  string // colored or stripped according to the color settings
+	Timestamp time.Time
+	Latency   time.Duration
+}
+
+// ParseFormat parses a response template such as
+// "{{.Timestamp.Format \"15:04:05\"}} {{.Command}}: {{.Response}}"
+func ParseFormat(text string) (*template.Template, error) {
+	tmpl, err := template.New("format").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// printFormatted renders the response with the configured template,
+// ending it with a newline if the template doesn't
+func (c *RCONClient) printFormatted(command, body string, latency time.Duration) {
+	var out strings.Builder
+	err := c.config.Format.Execute(&out, FormatData{
+		Command:   command,
+		Response:  c.renderText(body),
+		Timestamp: time.Now(),
+		Latency:   latency,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+
+	fmt.Print(out.String())
+	if !strings.HasSuffix(out.String(), "\n") {
+		fmt.Println()
+	}
+}
@@ -0,0 +1,65 @@
+package mcrcon
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		config Config
+		want   string
+	}{
+		{"{{.Command}}: {{.Response}}", Config{DisableColors: true}, "list: There are 0 of a max of 20 players online\n"},
+		{"{{.Command}} -> {{.Response}}\n", Config{DisableColors: true, OneLine: true}, "list -> There are 0 of a max of 20 players online\n"},
+		{"{{printf \"%q\" .Response}}", Config{}, "\"\\x1b[0;1;32mThere are 0 of a max of 20 players online\\x1b[0m\\n\\x1b[0m\"\n"},
+		{"{{len .Command}}", Config{}, "4\n"},
+	}
+
+	for _, tt := range tests {
+		config := tt.config
+		tmpl, err := ParseFormat(tt.format)
+		if err != nil {
+			t.Fatalf("ParseFormat(%q): %v", tt.format, err)
+		}
+		config.Format = tmpl
+		client := newAuthenticatedClient(t, &config, rconHandler(func(string) string {
+			return "§aThere are 0 of a max of 20 players online\n"
+		}))
+
+		got := captureStdout(t, func() {
+			if err := client.ExecuteCommand("list"); err != nil {
+				t.Errorf("ExecuteCommand: %v", err)
+			}
+		})
+		if got != tt.want {
+			t.Errorf("format %q printed %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestFormatTimestampAndLatency(t *testing.T) {
+	tmpl, err := ParseFormat(`{{.Timestamp.Format "15:04:05"}} {{.Command}} took {{.Latency}}`)
+	if err != nil {
+		t.Fatalf("ParseFormat: %v", err)
+	}
+	client := newAuthenticatedClient(t, &Config{Format: tmpl}, rconHandler(echo))
+
+	got := captureStdout(t, func() {
+		if err := client.ExecuteCommand("seed"); err != nil {
+			t.Errorf("ExecuteCommand: %v", err)
+		}
+	})
+	if !regexp.MustCompile(`^\d\d:\d\d:\d\d seed took [0-9.]+[µnm]?s\n$`).MatchString(got) {
+		t.Errorf("printed %q", got)
+	}
+}
+
+func TestParseFormatInvalid(t *testing.T) {
+	for _, format := range []string{"{{.Command", "{{end}}", "{{nosuchfunc .Response}}"} {
+		if _, err := ParseFormat(format); err == nil {
+			t.Errorf("ParseFormat(%q) succeeded, want an error", format)
+		}
+	}
+}
@@ -1,37 +1,137 @@
 package mcrcon
 
 import (
-    "fmt"
+	"fmt"
 )
 
 func PrintHelp() {
 	fmt.Printf(`Usage: %s [OPTIONS] [COMMANDS]
+       %s [OPTIONS] exec|shell|ping|info [COMMANDS]
 
 Send rcon commands to Minecraft server.
 
+Subcommands:
+  exec <commands...>            Run commands (the default when no subcommand is given)
+  shell                         Terminal mode (like -t)
+  ping                          Measure command round-trip time (like --ping)
+  info                          Print the server brand and version (like --info)
+
 Options:
-  -H		Server address (default: localhost)
-  -P		Port (default: 25575)
-  -p		Rcon password
-  -t		Terminal mode
-  -s		Silent mode
-  -c		Disable colors
-  -r		Output raw packets
-  -w		Wait for specified duration (seconds) between each command (1-600s)
-  -h		Print usage
-  -v		Version information
-
-Server address, port and password can be set with following environment variables:
+  -H, --host <address>          Server address (default: localhost)
+  -P, --port <port>             Port (default: 25575)
+  -p, --password <password>     Rcon password
+      --password-stdin          Read the password from the first line of standard input
+      --password-command <cmd>  Run a shell command, e.g. a secret manager, and use the first line it prints as the password
+      --server-dir <path>       Read the port and password from the server.properties of a local server
+      --config <path>           Read settings from a config file (default: ~/.config/mcrcon/config)
+  -S, --profile <name>          Use the [profiles.name] section of the config file (default: [default])
+  -t, --terminal                Terminal mode
+      --quiet-auth              Don't print the "Logged in." banner in terminal mode
+      --no-prompt               Don't print the "> " prompt in terminal mode
+      --no-stop-break           Stay in terminal mode after the stop command
+      --stop-command <cmd>      Command that ends terminal mode after it is sent (default: stop)
+      --no-terminal             Never start terminal mode; fail if no commands are given (for scripts)
+  -s, --silent                  Silent mode
+  -c, --no-color                Disable colors
+      --ampersand-codes         Treat &-codes such as &a (used by many plugins) as color codes
+      --color-map <code=seq>    Override colors, e.g. '7=\033[0;90m' (comma-separated, repeatable)
+      --output-file <path>      Append each command and its response to a file, even when silent
+      --empty-marker <text>     Print text for empty responses (default: "(no output)" in terminal mode only)
+      --format <template>       Print responses with a Go template using .Command, .Response, .Timestamp
+                                and .Latency, e.g. '{{.Command}} => {{.Response}}'
+      --timing                  Print each command's round-trip time to stderr, e.g. [1.25ms]
+      --echo-command            Print "$ command" before each response (not with --format)
+      --max-body-bytes <n>      Truncate responses to n bytes, marked with "...[truncated]"
+      --escape-ansi             Write colors as [red]text[/red] tags instead of ANSI when not on a terminal and in --output-file
+      --oneline                 Print each response on a single line, collapsing newlines and whitespace
+  -r, --raw                     Output raw packets
+      --raw-hex                 Print the header and a hexdump of each received packet
+      --raw-type <n>            Debug: send one packet of type n instead of commands and dump the response
+      --raw-body <text>         Debug: body of the --raw-type packet
+      --hexdump-sent            Print the header and a hexdump of each sent packet to stderr, password included
+  -w, --wait <duration>         Wait between each command, e.g. 500ms or 2s; plain numbers are seconds
+      --jitter <duration>       Randomize each wait by up to this much earlier or later
+  -e, --exec <command>          Run a command (repeatable; may start with a dash)
+  -f, --command-file <path>     Read commands from file (one per line, '#' starts a comment, a trailing '\' continues a line)
+      --stdin                   Run commands read line by line from standard input until EOF
+      --prefix <text>           Prepend text and a space to every command, e.g. "execute in world_nether run"
+      --var <name=value>        Set a variable for ${name} in commands (repeatable; falls back to the environment)
+      --allow-unset             Expand unset variables to nothing instead of failing the command
+      --count <n>               Run the commands n times (0: until interrupted) and print a summary
+      --announce <message>      Count down with "say message" before running the commands; %%d is the seconds left
+      --at <offsets>            When to announce, in seconds or durations before the commands (default: 60,30,10,5)
+      --interval <dur>          Run the commands every interval until interrupted; the first interrupt ends the cycle
+      --cycle-reconnect         Connect again before each --interval cycle instead of reusing the connection
+      --pipeline                Send all commands before reading the responses (faster, for commands that don't depend on each other)
+      --max-commands <n>        Refuse to run anything if more than n commands are given
+  -k, --keep-going              Keep going after a failed command
+      --fail-on <pattern>       Fail commands whose response matches a substring or regular expression
+      --allow <pattern>         Only send commands starting with this prefix or regular expression (repeatable)
+      --deny <pattern>          Never send commands starting with this prefix or regular expression (repeatable, overrides --allow)
+  -n, --dry-run                 Print commands and waits without connecting
+      --follow                  In terminal mode, keep printing output that arrives after a response
+      --follow-window <dur>     How long to wait for more output when following (default: 200ms)
+      --drain                   Discard stray data before each command, recovering from partial responses
+      --no-retry                Fail immediately if the first connection attempt fails
+      --retry-timeouts <n>      Resend a command up to n times if its response times out (may run it twice)
+      --reconnect               Reconnect and retry once when the connection drops or the session expires
+      --wait-for-server <dur>   Keep trying to connect and log in for up to dur, e.g. while the server starts
+      --wait-for-reconnect <dur>
+                                After a command such as stop drops the connection, keep reconnecting for up to dur
+      --srv                     Unless a port is given, connect to the target of the host's _rcon._tcp SRV record
+      --big-endian              Use big-endian packet framing for servers that need it
+      --tls                     Connect over TLS, e.g. through a TLS-terminating proxy
+      --tls-insecure            Use TLS without verifying the server certificate
+      --tls-ca <path>           Use TLS, verifying the server against the CA certificates in a PEM file
+      --proxy <url>             Connect through a SOCKS5 proxy (socks5://[user:pass@]host:port)
+      --charset <name>          Server text encoding: utf-8 (default), gbk, shift-jis, iso-8859-1, windows-1252, ...
+      --unix <path>             Connect to a Unix domain socket instead of a host and port (or -H unix:///path)
+      --source-addr <ip>        Connect from this local address, e.g. to use a specific interface
+      --tcp-keepalive <dur>     TCP keep-alive period, 0 to disable (default: 30s)
+      --keepalive-interval <dur>
+                                In terminal mode, send an empty command after dur without commands
+      --metrics-file <path>     Write Prometheus textfile metrics after running commands
+      --request-id <id>         Base request packet ID, positive, incremented per packet (default: 0xBADC0DE)
+      --listen <addr>           Serve an HTTP bridge: POST /command with JSON {"cmd": "..."} (default host: 127.0.0.1)
+      --info                    Print the server brand and version (Spigot, Paper and similar) and exit
+      --players                 Print the player count, limit and names as JSON and exit
+      --ping                    Measure command round-trip time in milliseconds and exit
+      --verbose                 Log connection attempts and packets to stderr
+  -h, --help                    Print usage
+  -v, --version                 Version information
+
+Options take values as "-H value", "-Hvalue", "--host value" or "--host=value".
+Short options without values can be combined, e.g. -sc. Arguments after "--"
+are always treated as commands, as are arguments containing spaces and
+negative numbers, so "say -1 point" is sent as a command.
+
+Server address, port, password and proxy can be set with following environment variables:
   MCRCON_HOST
   MCRCON_PORT
   MCRCON_PASS
+  MCRCON_PROXY
 
 - mcrcon will start in terminal mode if no commands are given
-- Command-line options will override environment variables
+- Command-line options override config file settings, which override
+  environment variables
+- The config file has "option = value" lines using long option names, e.g.
+  "host = mc.example.com" or "no-color = true"; lines after a
+  [profiles.name] header apply only with --profile name, and lines after
+  [default] only when no profile is given
+- IPv6 addresses can be given bare (-H ::1, -H fe80::1%%eth0) or in brackets;
+  pass the port separately with -P, or inline as -H [::1]:25575
 - Rcon commands with spaces must be enclosed in quotes
+- To send a command named like a subcommand, use -e, e.g. -e info
+- Commands given with -e run first, then commands from a file given with -f,
+  then the remaining commands on the command line
+- With --var or --allow-unset, ${name} in commands is replaced by a --var
+  value or environment variable; write $$ for a literal $
+- Exit status is 2 when the server rejects the password, 1 on other errors;
+  with --keep-going it is 10 plus the number of failed commands, capped at 125
 
-Example:
+Examples:
 	%s -H my.minecraft.server -p password -w 5 "say Server is restarting!" save-all stop
+	%s -p password --announce "Restarting in %%d seconds" --at 60,30,10 save-all stop
 
-`, AppName, AppName)
+`, AppName, AppName, AppName, AppName)
 }
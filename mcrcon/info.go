@@ -0,0 +1,90 @@
+package mcrcon
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// unknownCommandPrefixes start the responses of servers that don't
+// implement the version command, such as vanilla servers
+var unknownCommandPrefixes = []string{
+	"unknown command",
+	"unknown or incomplete command",
+}
+
+// ServerInfo runs the version command, which Bukkit-based servers such as
+// Spigot and Paper implement, and returns the server brand and version,
+// e.g. "Paper version git-Paper-196 (MC: 1.20.1)". It returns an error
+// wrapping ErrInfoUnavailable on servers without the command.
+func (c *RCONClient) ServerInfo() (string, error) {
+	body, err := c.Send("version")
+	if err != nil {
+		return "", err
+	}
+	return parseServerInfo(body)
+}
+
+// parseServerInfo extracts the brand and version from a version response
+func parseServerInfo(body string) (string, error) {
+	line, _, _ := strings.Cut(strings.TrimSpace(stripColorCodes(body, false)), "\n")
+	line = strings.TrimSpace(line)
+
+	lower := strings.ToLower(line)
+	if line == "" {
+		return "", fmt.Errorf("%w: empty response to version command", ErrInfoUnavailable)
+	}
+	for _, prefix := range unknownCommandPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return "", fmt.Errorf("%w: %s", ErrInfoUnavailable, line)
+		}
+	}
+
+	return strings.TrimPrefix(line, "This server is running "), nil
+}
+
+// listPattern matches the list response of vanilla ("There are 3 of a max
+// of 20 players online: a, b, c"), 1.12 and older ("There are 3/20 players
+// online:") and Bukkit-based servers ("There are 3 out of maximum 20
+// players online."), capturing the counts and what follows
+var listPattern = regexp.MustCompile(`(?s)There are (\d+)(?: of a max of |/| out of maximum )(\d+) players online[:.]?(.*)`)
+
+// ListPlayers runs the list command and returns the number of players
+// online, the player limit and the names of the players online
+func (c *RCONClient) ListPlayers() (online int, max int, names []string, err error) {
+	body, err := c.Send("list")
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return parseList(body)
+}
+
+// parseList parses a list response. Names may follow on the same line or
+// on following lines, which Bukkit-based servers prefix with a group name
+// such as "default: ".
+func parseList(body string) (online int, max int, names []string, err error) {
+	text := stripColorCodes(body, false)
+
+	m := listPattern.FindStringSubmatch(text)
+	if m == nil {
+		return 0, 0, nil, fmt.Errorf("unrecognized list response: %s", strings.TrimSpace(text))
+	}
+	online, _ = strconv.Atoi(m[1])
+	max, _ = strconv.Atoi(m[2])
+
+	names = []string{}
+	for _, line := range strings.Split(m[3], "\n") {
+		// Player names can't contain colons, so anything before one is a group
+		if i := strings.LastIndex(line, ":"); i >= 0 {
+			line = line[i+1:]
+		}
+		for _, name := range strings.Split(line, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return online, max, names, nil
+}
@@ -0,0 +1,77 @@
+package mcrcon
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseServerInfo(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"This server is running Paper version git-Paper-196 (MC: 1.20.1) (Implementing API version 1.20.1-R0.1-SNAPSHOT)\nYou are running the latest version",
+			"Paper version git-Paper-196 (MC: 1.20.1) (Implementing API version 1.20.1-R0.1-SNAPSHOT)"},
+		{"§fThis server is running CraftBukkit version 3869-Spigot-d2eba2c-3f9263b (MC: 1.20.1)\n",
+			"CraftBukkit version 3869-Spigot-d2eba2c-3f9263b (MC: 1.20.1)"},
+	}
+
+	for _, tt := range tests {
+		got, err := parseServerInfo(tt.body)
+		if err != nil {
+			t.Errorf("parseServerInfo(%q): %v", tt.body, err)
+		} else if got != tt.want {
+			t.Errorf("parseServerInfo(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestParseServerInfoUnavailable(t *testing.T) {
+	for _, body := range []string{
+		"",
+		"Unknown command. Type \"/help\" for help.",
+		"§cUnknown or incomplete command, see below for error\n§cversion<--[HERE]",
+	} {
+		if _, err := parseServerInfo(body); !errors.Is(err, ErrInfoUnavailable) {
+			t.Errorf("parseServerInfo(%q) returned %v, want ErrInfoUnavailable", body, err)
+		}
+	}
+}
+
+func TestParseList(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		online int
+		max    int
+		names  []string
+	}{
+		{"vanilla", "There are 3 of a max of 20 players online: Steve, Alex, Notch", 3, 20, []string{"Steve", "Alex", "Notch"}},
+		{"empty", "There are 0 of a max of 20 players online: ", 0, 20, []string{}},
+		{"old vanilla", "There are 1/10 players online:\nSteve", 1, 10, []string{"Steve"}},
+		{"colored", "§6There are §c2§6 out of maximum §c50§6 players online.\n§6default§r: §fSteve§f, §fAlex", 2, 50, []string{"Steve", "Alex"}},
+		{"groups", "There are 3 out of maximum 50 players online.\nadmins: Notch\ndefault: Steve, Alex", 3, 50, []string{"Notch", "Steve", "Alex"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			online, max, names, err := parseList(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if online != tt.online || max != tt.max {
+				t.Errorf("counts = %d/%d, want %d/%d", online, max, tt.online, tt.max)
+			}
+			if !slices.Equal(names, tt.names) {
+				t.Errorf("names = %q, want %q", names, tt.names)
+			}
+		})
+	}
+}
+
+func TestParseListUnrecognized(t *testing.T) {
+	if _, _, _, err := parseList("Unknown command"); err == nil {
+		t.Error("parseList accepted an unrecognized response")
+	}
+}
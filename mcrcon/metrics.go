@@ -0,0 +1,102 @@
+package mcrcon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds in seconds of the command latency histogram
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics records command counts and latencies. Set Config.Metrics to
+// collect them and call WriteTextfile to export them for Prometheus.
+type Metrics struct {
+	mu       sync.Mutex
+	commands uint64
+	failures uint64
+	buckets  []uint64 // cumulative counts per latencyBuckets entry
+	sum      time.Duration
+}
+
+// observe records one executed command
+func (m *Metrics) observe(latency time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.buckets == nil {
+		m.buckets = make([]uint64, len(latencyBuckets))
+	}
+
+	m.commands++
+	if err != nil {
+		m.failures++
+	}
+
+	m.sum += latency
+	for i, bound := range latencyBuckets {
+		if latency.Seconds() <= bound {
+			m.buckets[i]++
+		}
+	}
+}
+
+// WriteTextfile writes the metrics in the Prometheus text format, as read
+// by node_exporter's textfile collector. The file is replaced atomically.
+func (m *Metrics) WriteTextfile(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+
+	fmt.Fprintln(&b, "# HELP mcrcon_commands_total Number of rcon commands executed.")
+	fmt.Fprintln(&b, "# TYPE mcrcon_commands_total counter")
+	fmt.Fprintf(&b, "mcrcon_commands_total %d\n", m.commands)
+
+	fmt.Fprintln(&b, "# HELP mcrcon_command_failures_total Number of rcon commands that failed.")
+	fmt.Fprintln(&b, "# TYPE mcrcon_command_failures_total counter")
+	fmt.Fprintf(&b, "mcrcon_command_failures_total %d\n", m.failures)
+
+	fmt.Fprintln(&b, "# HELP mcrcon_command_duration_seconds Round-trip time of rcon commands.")
+	fmt.Fprintln(&b, "# TYPE mcrcon_command_duration_seconds histogram")
+	for i, bound := range latencyBuckets {
+		var count uint64
+		if m.buckets != nil {
+			count = m.buckets[i]
+		}
+		fmt.Fprintf(&b, "mcrcon_command_duration_seconds_bucket{le=\"%g\"} %d\n", bound, count)
+	}
+	fmt.Fprintf(&b, "mcrcon_command_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.commands)
+	fmt.Fprintf(&b, "mcrcon_command_duration_seconds_sum %g\n", m.sum.Seconds())
+	fmt.Fprintf(&b, "mcrcon_command_duration_seconds_count %d\n", m.commands)
+
+	fmt.Fprintln(&b, "# HELP mcrcon_last_run_timestamp_seconds Time the metrics were written.")
+	fmt.Fprintln(&b, "# TYPE mcrcon_last_run_timestamp_seconds gauge")
+	fmt.Fprintf(&b, "mcrcon_last_run_timestamp_seconds %d\n", time.Now().Unix())
+
+	// Write to a temporary file first so collectors never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".mcrcon-metrics-*")
+	if err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package mcrcon
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"time"
+)
+
+// Option configures a client created with New
+type Option func(*Config)
+
+// New creates a client for the server at host without connecting to it;
+// call Connect and then Authenticate before sending commands. Options
+// adjust the defaults, which are the default port and no password:
+//
+//	client, err := mcrcon.New("mc.example.com",
+//		mcrcon.WithPassword("secret"),
+//		mcrcon.WithTimeout(5*time.Second),
+//	)
+//
+// NewRCONClient takes a Config directly and connects immediately.
+func New(host string, opts ...Option) (*RCONClient, error) {
+	config := &Config{Host: host, Port: DefaultPort}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return NewClientWithConn(nil, config), nil
+}
+
+// WithPort sets the server port
+func WithPort(port string) Option {
+	return func(c *Config) { c.Port = port }
+}
+
+// WithPassword sets the rcon password
+func WithPassword(password string) Option {
+	return func(c *Config) { c.Password = password }
+}
+
+// WithTimeout sets the limit for connecting and for waiting for a response
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Config) { c.Timeout = timeout }
+}
+
+// WithLogger sets the logger that receives debug logs of connections and packets
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Config) { c.Logger = logger }
+}
+
+// WithTLS connects over TLS using tlsConfig, which may be nil for the defaults
+func WithTLS(tlsConfig *tls.Config) Option {
+	return func(c *Config) {
+		c.TLS = true
+		c.TLSConfig = tlsConfig
+	}
+}
+
+// WithProxy connects through a SOCKS5 proxy such as socks5://host:1080
+func WithProxy(proxyURL string) Option {
+	return func(c *Config) { c.Proxy = proxyURL }
+}
+
+// WithDialer opens connections with d instead of the default TCP dialer
+func WithDialer(d Dialer) Option {
+	return func(c *Config) { c.Dialer = d }
+}
@@ -0,0 +1,65 @@
+package mcrcon
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		check func(c *Config) bool
+	}{
+		{"defaults", nil, func(c *Config) bool {
+			return c.Port == DefaultPort && c.Password == "" && c.timeout() == defaultTimeout
+		}},
+		{"port and password", []Option{WithPort("25566"), WithPassword("secret")}, func(c *Config) bool {
+			return c.Port == "25566" && c.Password == "secret"
+		}},
+		{"timeout and TLS", []Option{WithTimeout(time.Second), WithTLS(tlsConfig)}, func(c *Config) bool {
+			return c.timeout() == time.Second && c.TLS && c.TLSConfig == tlsConfig
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := New("mc.example.com", tt.opts...)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if client.config.Host != "mc.example.com" || !tt.check(client.config) {
+				t.Errorf("New set %+v", client.config)
+			}
+		})
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	if _, err := New("mc.example.com", WithPort("0")); err == nil {
+		t.Error("New accepted port 0")
+	}
+}
+
+func TestNewConnect(t *testing.T) {
+	host, port := listen(t, rconHandler(echo))
+
+	client, err := New(host, WithPort(port), WithPassword(testPassword), WithTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if err := client.Authenticate(); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if body, err := client.Send("list"); err != nil || body != "list" {
+		t.Errorf("Send = %q, %v, want %q", body, err, "list")
+	}
+}
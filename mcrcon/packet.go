@@ -1,11 +1,24 @@
 package mcrcon
 
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
 // RCON packet types
 const (
-	rconExecCommand    = 2
-	rconAuthenticate   = 3
+	rconResponseValue = 0
+	rconExecCommand   = 2
+	rconAuthResponse  = 2
+	rconAuthenticate  = 3
 )
 
+// packetOverhead is the number of bytes a packet's Size counts besides the
+// body: ID (4) + Type (4) + body null terminator (1) + padding (1)
+const packetOverhead = 10
+
 // RCONPacket represents an RCON protocol packet
 type RCONPacket struct {
 	Size int32
@@ -13,3 +26,80 @@ type RCONPacket struct {
 	Type int32
 	Body string
 }
+
+// Encode returns the packet in wire format, with little-endian integers
+// as servers expect, and sets Size to match the body
+func (p *RCONPacket) Encode() []byte {
+	return p.encode(binary.LittleEndian)
+}
+
+// encode implements Encode with the given byte order
+func (p *RCONPacket) encode(order binary.ByteOrder) []byte {
+	// Size = ID (4) + Type (4) + Body (n) + null terminator (1) + padding (1)
+	p.Size = int32(len(p.Body) + packetOverhead)
+
+	buf := make([]byte, 4+p.Size)
+	order.PutUint32(buf[0:4], uint32(p.Size))
+	order.PutUint32(buf[4:8], uint32(p.ID))
+	order.PutUint32(buf[8:12], uint32(p.Type))
+	copy(buf[12:], p.Body)
+	// Null terminators already zero in buffer
+
+	return buf
+}
+
+// DecodePacket reads one packet in wire format from r. Packets with a
+// Size outside what servers can send are rejected, and errors caused by
+// a closed connection wrap ErrConnClosed.
+func DecodePacket(r io.Reader) (*RCONPacket, error) {
+	packet, _, err := decodePacket(r, binary.LittleEndian, nil)
+	return packet, err
+}
+
+// decodePacket implements DecodePacket with the given byte order, reading
+// into buf if it's large enough for any packet. It also returns the raw
+// packet bytes, which are only valid until buf is reused.
+func decodePacket(r io.Reader, order binary.ByteOrder, buf []byte) (*RCONPacket, []byte, error) {
+	if len(buf) < 4+maxResponseSize {
+		buf = make([]byte, 4+maxResponseSize)
+	}
+
+	// Read size
+	if _, err := io.ReadFull(r, buf[:4]); err != nil {
+		return nil, nil, fmt.Errorf("failed to read packet size: %w", wrapConnError(err))
+	}
+	size := int32(order.Uint32(buf[:4]))
+
+	// Validate size
+	if size < packetOverhead || size > maxResponseSize {
+		return nil, nil, fmt.Errorf("invalid packet size: %d (must be %d-%d)", size, packetOverhead, maxResponseSize)
+	}
+
+	// Read the rest of the packet
+	payload := buf[4 : 4+size]
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, nil, fmt.Errorf("failed to read packet payload: %w", wrapConnError(err))
+	}
+
+	// The body is followed by a null terminator and a null padding byte
+	if payload[size-2] != 0 || payload[size-1] != 0 {
+		return nil, nil, fmt.Errorf("malformed packet: expected two null bytes after the body, got %#02x %#02x", payload[size-2], payload[size-1])
+	}
+
+	// Body is from byte 8 to size-2 (excluding two null terminators)
+	packet := &RCONPacket{
+		Size: size,
+		ID:   int32(order.Uint32(payload[0:4])),
+		Type: int32(order.Uint32(payload[4:8])),
+		Body: string(payload[8 : size-2]),
+	}
+
+	return packet, buf[:4+size], nil
+}
+
+// dumpPacket writes a decoded header line followed by a hexdump of the
+// packet's bytes as sent or received, including the size field
+func dumpPacket(w io.Writer, packet *RCONPacket, raw []byte) {
+	fmt.Fprintf(w, "Size=%d ID=%d Type=%d\n", packet.Size, packet.ID, packet.Type)
+	fmt.Fprint(w, hex.Dump(raw))
+}
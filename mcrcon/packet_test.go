@@ -0,0 +1,159 @@
+package mcrcon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"command", "say héllo"},
+		{"empty body", ""},
+		{"maximum body", strings.Repeat("x", maxResponseSize-packetOverhead)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packet := &RCONPacket{ID: 42, Type: rconExecCommand, Body: tt.body}
+			raw := packet.Encode()
+
+			if packet.Size != int32(len(tt.body)+packetOverhead) {
+				t.Errorf("Encode set Size %d, want %d", packet.Size, len(tt.body)+packetOverhead)
+			}
+			if len(raw) != 4+int(packet.Size) {
+				t.Errorf("encoded %d bytes, want %d", len(raw), 4+packet.Size)
+			}
+
+			decoded, err := DecodePacket(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatalf("DecodePacket: %v", err)
+			}
+			if *decoded != *packet {
+				t.Errorf("DecodePacket = %+v, want %+v", decoded, packet)
+			}
+		})
+	}
+}
+
+// rawPacket builds a packet in wire format with the given Size and payload
+// after the ID and type, which need not be consistent with each other
+func rawPacket(size int32, payload string) []byte {
+	buf := binary.LittleEndian.AppendUint32(nil, uint32(size))
+	buf = binary.LittleEndian.AppendUint32(buf, 1)
+	buf = binary.LittleEndian.AppendUint32(buf, rconResponseValue)
+	return append(buf, payload...)
+}
+
+func TestDecodeMalformedPacket(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+		want string
+	}{
+		{"missing terminators", rawPacket(12, "abcd"), "expected two null bytes"},
+		{"missing padding", rawPacket(12, "abc\x00"), "expected two null bytes"},
+		{"too small", rawPacket(packetOverhead-1, "\x00\x00"), "invalid packet size"},
+		{"too large", rawPacket(maxResponseSize+1, ""), "invalid packet size"},
+		{"negative size", rawPacket(-1, ""), "invalid packet size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodePacket(bytes.NewReader(tt.raw))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("DecodePacket returned %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeTruncatedPacket(t *testing.T) {
+	raw := (&RCONPacket{ID: 1, Body: "list"}).Encode()
+
+	for _, n := range []int{0, 2, 4, len(raw) - 1} {
+		_, err := DecodePacket(bytes.NewReader(raw[:n]))
+		if !errors.Is(err, ErrConnClosed) {
+			t.Errorf("DecodePacket of %d bytes returned %v, want ErrConnClosed", n, err)
+		}
+	}
+}
+
+func TestDumpPacket(t *testing.T) {
+	packet := &RCONPacket{Size: 14, ID: 1, Type: rconExecCommand, Body: "list"}
+	raw := encodeTestPacket(packet)
+
+	var out strings.Builder
+	dumpPacket(&out, packet, raw)
+
+	want := "Size=14 ID=1 Type=2\n" +
+		"00000000  0e 00 00 00 01 00 00 00  02 00 00 00 6c 69 73 74  |............list|\n" +
+		"00000010  00 00                                             |..|\n"
+	if out.String() != want {
+		t.Errorf("dumpPacket wrote\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestHexdumpSent(t *testing.T) {
+	client := newAuthenticatedClient(t, &Config{RequestID: 1}, rconHandler(echo))
+	client.config.HexdumpSent = true
+
+	stderr := captureStderr(t, func() {
+		if _, err := client.Send("list"); err != nil {
+			t.Errorf("Send: %v", err)
+		}
+	})
+
+	// The auth packet used ID 1, so the command has ID 2
+	want := "Size=14 ID=2 Type=2\n" +
+		"00000000  0e 00 00 00 02 00 00 00  02 00 00 00 6c 69 73 74  |............list|\n" +
+		"00000010  00 00                                             |..|\n"
+	if stderr != want {
+		t.Errorf("--hexdump-sent wrote\n%s\nwant\n%s", stderr, want)
+	}
+}
+
+// replayConn is a connection that reads the same bytes again after each
+// reset and ignores deadlines
+type replayConn struct {
+	net.Conn
+	r *bytes.Reader
+}
+
+func (c *replayConn) Read(b []byte) (int, error)      { return c.r.Read(b) }
+func (c *replayConn) SetReadDeadline(time.Time) error { return nil }
+func (c *replayConn) Close() error                    { return nil }
+
+func BenchmarkReceivePacket(b *testing.B) {
+	raw := encodeTestPacket(&RCONPacket{ID: 1, Body: strings.Repeat("x", 1000)})
+	conn := &replayConn{r: bytes.NewReader(raw)}
+	client := NewClientWithConn(conn, &Config{})
+
+	b.ReportAllocs()
+	for b.Loop() {
+		conn.r.Reset(raw)
+		if _, err := client.receivePacket(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSend(b *testing.B) {
+	client := newAuthenticatedClient(b, &Config{}, rconHandler(func(string) string {
+		return strings.Repeat("x", 1000)
+	}))
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := client.Send("list"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
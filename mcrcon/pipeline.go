@@ -0,0 +1,168 @@
+package mcrcon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SendPipelined sends all commands before reading any response, then
+// matches the responses to the commands by request ID, which saves a
+// round trip per command on slow links. The bodies are returned in the
+// order of the commands, along with each command's latency. Commands
+// whose outcome depends on an earlier one shouldn't be pipelined.
+func (c *RCONClient) SendPipelined(commands []string) ([]string, []time.Duration, error) {
+	bodies := make([]string, len(commands))
+	latencies := make([]time.Duration, len(commands))
+	if c.config.DryRun || len(commands) == 0 {
+		return bodies, latencies, nil
+	}
+
+	packets := make([]*RCONPacket, len(commands))
+	for i, command := range commands {
+		body, err := c.config.encodeCommand(command)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%q: %w", command, err)
+		}
+		packets[i] = &RCONPacket{Type: rconExecCommand, Body: body}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Checked before the sender starts, which would otherwise race the
+	// receiver to report it
+	if c.conn == nil {
+		return nil, nil, ErrNotConnected
+	}
+
+	if c.config.Drain {
+		c.drain()
+	}
+
+	// Request IDs are consecutive, so a response's index is its offset
+	// from the first one
+	for _, packet := range packets {
+		packet.ID = c.requestID()
+	}
+	first := packets[0].ID
+
+	// Send while reading, so that a large batch can't fill the socket
+	// buffers in both directions and stall
+	start := time.Now()
+	sent := make(chan error, 1)
+	go func() {
+		for _, packet := range packets {
+			if err := c.sendPacket(packet); err != nil {
+				sent <- fmt.Errorf("failed to send command: %w", err)
+				return
+			}
+		}
+		c.lastSend.Store(time.Now().UnixNano())
+		sent <- nil
+	}()
+
+	if err := c.receivePipelined(packets, first, start, bodies, latencies); err != nil {
+		// Unblock the sender before giving up the connection; its error
+		// would only be caused by the deadline or by the same failure
+		c.conn.SetWriteDeadline(time.Now())
+		<-sent
+		c.conn.SetWriteDeadline(time.Time{})
+		return nil, nil, err
+	}
+	if err := <-sent; err != nil {
+		return nil, nil, err
+	}
+
+	return bodies, latencies, nil
+}
+
+// receivePipelined reads a response for each of the packets, storing
+// each body and latency at the index of its request
+func (c *RCONClient) receivePipelined(packets []*RCONPacket, first int32, start time.Time, bodies []string, latencies []time.Duration) error {
+	received := make([]bool, len(packets))
+	for pending := len(packets); pending > 0; {
+		response, err := c.receivePacket()
+		if err != nil {
+			if errors.Is(err, ErrConnClosed) {
+				return fmt.Errorf("%w: %w", ErrClosedAfterSend, err)
+			}
+			return fmt.Errorf("failed to receive response: %w", err)
+		}
+
+		if c.isEarlierID(response.ID, first) {
+			c.config.logger().Debug("discarding stale response", "id", response.ID, "expected", first)
+			continue
+		}
+
+		if response.ID == -1 {
+			c.authenticated = false
+			return ErrReauthRequired
+		}
+
+		i := int(response.ID - first)
+		if i < 0 || i >= len(packets) || received[i] {
+			return unexpectedIDError(first, response)
+		}
+		bodies[i] = truncateBody(response.Body, c.config.MaxBodyBytes)
+		latencies[i] = time.Since(start)
+		received[i] = true
+		pending--
+	}
+	return nil
+}
+
+// RunPipelined executes commands like RunCommands, but sends them all
+// before reading the responses (see SendPipelined), so Wait and Jitter
+// don't apply. Responses are handled in the order of the commands.
+func (c *RCONClient) RunPipelined(commands []string) int {
+	if err := c.config.checkCommandCount(len(commands)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	prepared := make([]string, 0, len(commands))
+	failed := 0
+	for _, command := range commands {
+		command, err := c.config.prepareCommand(command)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Command failed: %v\n", err)
+			failed++
+			if !c.config.KeepGoing {
+				return 1
+			}
+			continue
+		}
+		if c.config.DryRun {
+			fmt.Println(command)
+		}
+		prepared = append(prepared, command)
+	}
+
+	if c.config.DryRun {
+		return exitStatus(failed, false, c.config.KeepGoing)
+	}
+
+	bodies, latencies, err := c.SendPipelined(prepared)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Command failed: %v\n", err)
+		return 1
+	}
+
+	for i, command := range prepared {
+		err := c.handleResponse(command, bodies[i], latencies[i], nil)
+		if c.config.Metrics != nil {
+			c.config.Metrics.observe(latencies[i], err)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Command failed: %v\n", err)
+			failed++
+		}
+	}
+
+	if failed > 0 && c.config.KeepGoing {
+		fmt.Fprintf(os.Stderr, "%d of %d commands failed\n", failed, len(commands))
+	}
+	return exitStatus(failed, false, c.config.KeepGoing)
+}
@@ -0,0 +1,54 @@
+package mcrcon
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSendPipelinedOutOfOrder(t *testing.T) {
+	commands := []string{"list", "seed", "time query daytime", "difficulty"}
+
+	// Hold the responses until every command has arrived, then send them
+	// in reverse order
+	var pending []*RCONPacket
+	handle := rconHandler(func(command string) string { return "re: " + command })
+	client := newAuthenticatedClient(t, &Config{}, func(p *RCONPacket) []*RCONPacket {
+		if p.Type == rconAuthenticate {
+			return handle(p)
+		}
+		pending = append(pending, handle(p)...)
+		if len(pending) < len(commands) {
+			return nil
+		}
+		slices.Reverse(pending)
+		return pending
+	})
+
+	bodies, latencies, err := client.SendPipelined(commands)
+	if err != nil {
+		t.Fatalf("SendPipelined: %v", err)
+	}
+	for i, command := range commands {
+		if want := "re: " + command; bodies[i] != want {
+			t.Errorf("response to %q = %q, want %q", command, bodies[i], want)
+		}
+	}
+	if len(latencies) != len(commands) {
+		t.Errorf("got %d latencies for %d commands", len(latencies), len(commands))
+	}
+}
+
+func TestSendPipelinedDuplicateResponse(t *testing.T) {
+	handle := rconHandler(echo)
+	client := newAuthenticatedClient(t, &Config{}, func(p *RCONPacket) []*RCONPacket {
+		responses := handle(p)
+		if p.Type == rconExecCommand {
+			responses = append(responses, responses...)
+		}
+		return responses
+	})
+
+	if _, _, err := client.SendPipelined([]string{"list", "seed"}); err == nil {
+		t.Error("SendPipelined accepted two responses to the same command")
+	}
+}
@@ -0,0 +1,42 @@
+package mcrcon
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ParseCommandPattern compiles an Allow or Deny pattern. Patterns match
+// at the start of a command, ignoring case, so a plain word like "say"
+// matches every say command and "time (set|query)" is a regular
+// expression for two subcommands.
+func ParseCommandPattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("(?i)^(?:" + pattern + ")")
+	if err != nil {
+		return nil, fmt.Errorf("invalid command pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// checkCommand returns ErrCommandDenied if the command matches a Deny
+// pattern or, when there are Allow patterns, matches none of them. A
+// leading slash, as typed in game, is ignored.
+func (c *Config) checkCommand(command string) error {
+	command = strings.TrimPrefix(strings.TrimSpace(command), "/")
+
+	for _, re := range c.Deny {
+		if re.MatchString(command) {
+			return fmt.Errorf("%w: %q matches %s", ErrCommandDenied, command, re)
+		}
+	}
+
+	if len(c.Allow) == 0 {
+		return nil
+	}
+	for _, re := range c.Allow {
+		if re.MatchString(command) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q is not allowed", ErrCommandDenied, command)
+}
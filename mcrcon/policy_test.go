@@ -0,0 +1,74 @@
+package mcrcon
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+// commandPatterns compiles command patterns for a test
+func commandPatterns(t *testing.T, patterns ...string) []*regexp.Regexp {
+	t.Helper()
+
+	var res []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := ParseCommandPattern(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, re)
+	}
+	return res
+}
+
+func TestCheckCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		deny    []string
+		allowed []string
+		denied  []string
+	}{
+		{
+			name:    "allow only",
+			allow:   []string{"list", "say", "time (set|query)"},
+			allowed: []string{"list", "SAY hello", "/time query daytime"},
+			denied:  []string{"stop", "time add 100", "op Steve"},
+		},
+		{
+			name:    "deny only",
+			deny:    []string{"stop", "op|deop"},
+			allowed: []string{"list", "say stop now"},
+			denied:  []string{"stop", "/STOP", "  deop Steve"},
+		},
+		{
+			name:    "combined",
+			allow:   []string{"say", "whitelist"},
+			deny:    []string{"whitelist (off|remove)"},
+			allowed: []string{"say hi", "whitelist add Steve"},
+			denied:  []string{"whitelist off", "whitelist remove Steve", "list"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Allow: commandPatterns(t, tt.allow...), Deny: commandPatterns(t, tt.deny...)}
+			for _, command := range tt.allowed {
+				if err := config.checkCommand(command); err != nil {
+					t.Errorf("checkCommand(%q) = %v, want nil", command, err)
+				}
+			}
+			for _, command := range tt.denied {
+				if err := config.checkCommand(command); !errors.Is(err, ErrCommandDenied) {
+					t.Errorf("checkCommand(%q) = %v, want ErrCommandDenied", command, err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCommandPatternInvalid(t *testing.T) {
+	if _, err := ParseCommandPattern("time (set"); err == nil {
+		t.Error("ParseCommandPattern accepted an unbalanced group")
+	}
+}
@@ -0,0 +1,148 @@
+package mcrcon
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// socks5Stub starts a SOCKS5 proxy without authentication that supports
+// CONNECT to IPv4 addresses, returning its address and a channel that
+// receives each address it connects to
+func socks5Stub(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	targets := make(chan string, 8)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				target, err := socks5Handshake(conn)
+				if err != nil {
+					return
+				}
+				targets <- target
+
+				upstream, err := net.Dial("tcp", target)
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+				// Reply with success and a zero bound address
+				conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+				go io.Copy(upstream, conn)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+
+	return ln.Addr().String(), targets
+}
+
+// socks5Handshake reads a client's greeting and CONNECT request,
+// returning the requested address
+func socks5Handshake(conn net.Conn) (string, error) {
+	// Greeting: version, number of methods, methods
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", err
+	}
+	if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+		return "", err
+	}
+	if _, err := conn.Write([]byte{5, 0}); err != nil {
+		return "", err
+	}
+
+	// Request: version, CONNECT, reserved, IPv4 address type, address, port
+	request := make([]byte, 10)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return "", err
+	}
+	ip := net.IP(request[4:8])
+	port := binary.BigEndian.Uint16(request[8:10])
+	return net.JoinHostPort(ip.String(), strconv.Itoa(int(port))), nil
+}
+
+func TestDialThroughProxy(t *testing.T) {
+	host, port := listen(t, rconHandler(echo))
+	proxyAddr, targets := socks5Stub(t)
+
+	client, err := NewRCONClient(&Config{
+		Host:     host,
+		Port:     port,
+		Password: testPassword,
+		Proxy:    "socks5://" + proxyAddr,
+	})
+	if err != nil {
+		t.Fatalf("NewRCONClient: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Authenticate(); err != nil {
+		t.Fatalf("Authenticate through the proxy: %v", err)
+	}
+	if body, err := client.Send("list"); err != nil || body != "list" {
+		t.Errorf("Send through the proxy = %q, %v", body, err)
+	}
+	if target := <-targets; target != net.JoinHostPort(host, port) {
+		t.Errorf("proxy connected to %s, want %s", target, net.JoinHostPort(host, port))
+	}
+}
+
+func TestInvalidProxy(t *testing.T) {
+	for _, proxy := range []string{"http://127.0.0.1:8080", "socks5://%zz"} {
+		config := &Config{Host: "localhost", Port: DefaultPort, Proxy: proxy}
+		if err := config.Validate(); err == nil {
+			t.Errorf("Validate accepted proxy %q", proxy)
+		}
+	}
+}
+
+func TestStalledProxyTimeout(t *testing.T) {
+	// The proxy accepts connections but never answers the handshake
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		<-done
+		conn.Close()
+	}()
+
+	start := time.Now()
+	_, err = NewRCONClient(&Config{
+		Host:    "127.0.0.1",
+		Port:    DefaultPort,
+		Proxy:   "socks5://" + ln.Addr().String(),
+		Timeout: 100 * time.Millisecond,
+		NoRetry: true,
+	})
+	if err == nil {
+		t.Fatal("NewRCONClient succeeded through a stalled proxy")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("NewRCONClient gave up after %s, want about the 100ms timeout", elapsed)
+	}
+}
@@ -0,0 +1,54 @@
+package mcrcon
+
+import (
+	"errors"
+	"fmt"
+)
+
+// QueueCommand holds a command until the client next authenticates,
+// whether on connecting or after a reconnect, and then runs it with
+// ExecuteCommand. Queued commands run in order. Together with
+// AutoReconnect, this lets callers keep issuing commands through a
+// brief outage. It returns ErrQueueFull once maxQueuedCommands commands
+// are waiting.
+func (c *RCONClient) QueueCommand(command string) error {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	if len(c.queue) >= maxQueuedCommands {
+		return fmt.Errorf("%w (%d commands)", ErrQueueFull, maxQueuedCommands)
+	}
+	c.queue = append(c.queue, command)
+	return nil
+}
+
+// flushQueue runs the queued commands in order. Failed commands are
+// logged and dropped, except that a lost connection stops the flush and
+// keeps the commands that weren't sent queued for the next
+// authentication. Failures go to the Logger rather than stderr, since
+// the flush happens inside Authenticate, where the caller didn't ask for
+// anything to be printed.
+func (c *RCONClient) flushQueue() {
+	c.queueMu.Lock()
+	pending := c.queue
+	c.queue = nil
+	c.queueMu.Unlock()
+
+	for i, command := range pending {
+		err := c.ExecuteCommand(command)
+		if errors.Is(err, ErrConnClosed) {
+			// A command the server closed the connection after was sent
+			if errors.Is(err, ErrClosedAfterSend) {
+				i++
+			}
+			c.queueMu.Lock()
+			c.queue = append(pending[i:], c.queue...)
+			c.queueMu.Unlock()
+			c.config.logger().Warn("connection lost while flushing the command queue", "kept", len(pending)-i, "error", err)
+			return
+		}
+		if err != nil {
+			c.config.logger().Warn("queued command failed", "command", command, "error", err)
+		}
+	}
+}
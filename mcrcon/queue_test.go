@@ -0,0 +1,85 @@
+package mcrcon
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestQueueCommandBeforeConnect(t *testing.T) {
+	var received []string
+	handle := rconHandler(echo)
+	client := newPipeClient(t, &Config{Password: testPassword, SilentMode: true}, func(p *RCONPacket) []*RCONPacket {
+		if p.Type == rconAuthenticate {
+			received = append(received, "(auth)")
+		} else {
+			received = append(received, p.Body)
+		}
+		return handle(p)
+	})
+
+	for _, command := range []string{"say hello", "list"} {
+		if err := client.QueueCommand(command); err != nil {
+			t.Fatalf("QueueCommand(%q): %v", command, err)
+		}
+	}
+	if len(received) != 0 {
+		t.Fatalf("server received %q before authentication", received)
+	}
+
+	if err := client.Authenticate(); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if want := []string{"(auth)", "say hello", "list"}; !slices.Equal(received, want) {
+		t.Errorf("server received %q, want %q", received, want)
+	}
+	if len(client.queue) != 0 {
+		t.Errorf("%d commands still queued after the flush", len(client.queue))
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	client, err := New("localhost", WithPassword(testPassword))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	for range maxQueuedCommands {
+		if err := client.QueueCommand("list"); err != nil {
+			t.Fatalf("QueueCommand: %v", err)
+		}
+	}
+	if err := client.QueueCommand("list"); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("QueueCommand returned %v, want ErrQueueFull", err)
+	}
+}
+
+func TestQueuedCommandFailureLogged(t *testing.T) {
+	var logs bytes.Buffer
+	config := &Config{
+		Password:   testPassword,
+		SilentMode: true,
+		Deny:       commandPatterns(t, "stop"),
+		Logger:     slog.New(slog.NewTextHandler(&logs, nil)),
+	}
+	client := newPipeClient(t, config, rconHandler(echo))
+
+	if err := client.QueueCommand("stop"); err != nil {
+		t.Fatalf("QueueCommand: %v", err)
+	}
+	var err error
+	stderr := captureStderr(t, func() { err = client.Authenticate() })
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	if stderr != "" {
+		t.Errorf("stderr = %q, want failures only in the log", stderr)
+	}
+	if !strings.Contains(logs.String(), "queued command failed") {
+		t.Errorf("log = %q, want the failed command", logs.String())
+	}
+}
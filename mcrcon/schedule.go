@@ -0,0 +1,47 @@
+package mcrcon
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// RunScheduled runs commands as a batch every interval, measured from the
+// start of each cycle, until stop or the client is closed. Each cycle
+// begins with a timestamped header on stderr and, with reconnect set, a
+// fresh connection. Closing stop lets the current cycle finish; closing
+// the client interrupts it. The result is 1 if any cycle failed.
+func (c *RCONClient) RunScheduled(commands []string, interval time.Duration, reconnect bool, stop <-chan struct{}) int {
+	if err := c.config.checkCommandCount(len(commands)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	clock := c.config.clock()
+	status := 0
+
+	for cycle := 1; ; cycle++ {
+		start := clock.Now()
+		fmt.Fprintf(os.Stderr, "[%s] Cycle %d\n", start.Format(time.RFC3339), cycle)
+
+		if reconnect && cycle > 1 && !c.config.DryRun {
+			if err := c.Reauthenticate(); err != nil {
+				fmt.Fprintf(os.Stderr, "Reconnect failed: %v\n", err)
+				status = 1
+			} else if c.RunCommands(commands) != 0 {
+				status = 1
+			}
+		} else if c.RunCommands(commands) != 0 {
+			status = 1
+		}
+
+		wait := max(interval-clock.Now().Sub(start), 0)
+		select {
+		case <-clock.After(wait):
+		case <-stop:
+			return status
+		case <-c.closed:
+			return status
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package mcrcon
+
+import (
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunScheduled(t *testing.T) {
+	const interval = 10 * time.Millisecond
+
+	tests := []struct {
+		name      string
+		reconnect bool
+		auths     int32
+	}{
+		{"reuse", false, 1},
+		{"reconnect", true, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The third wait never ends, so the run stops there
+			clock := &fakeClock{block: true, fire: 2}
+			var auths atomic.Int32
+			commands := make(chan string, 10)
+			record := recordingHandler(commands)
+			client := newAuthenticatedClient(t, &Config{SilentMode: true, Clock: clock}, func(p *RCONPacket) []*RCONPacket {
+				if p.Type == rconAuthenticate {
+					auths.Add(1)
+				}
+				return record(p)
+			})
+
+			stop := make(chan struct{})
+			var code int
+			stderr := captureStderr(t, func() {
+				done := make(chan int)
+				go func() { done <- client.RunScheduled([]string{"save-all"}, interval, tt.reconnect, stop) }()
+
+				for len(clock.recorded()) < 3 {
+					time.Sleep(time.Millisecond)
+				}
+				close(stop)
+				code = <-done
+			})
+			close(commands)
+
+			if code != 0 {
+				t.Errorf("RunScheduled returned %d, want 0", code)
+			}
+			if n := len(commands); n != 3 {
+				t.Errorf("server received %d commands, want 3", n)
+			}
+			if got, want := clock.recorded(), []time.Duration{interval, interval, interval}; !slices.Equal(got, want) {
+				t.Errorf("waits = %v, want %v", got, want)
+			}
+			for _, header := range []string{"] Cycle 1\n", "] Cycle 2\n", "] Cycle 3\n"} {
+				if !strings.Contains(stderr, header) {
+					t.Errorf("stderr = %q, want a %q header", stderr, header)
+				}
+			}
+
+			if n := auths.Load(); n != tt.auths {
+				t.Errorf("authenticated %d times, want %d", n, tt.auths)
+			}
+		})
+	}
+}
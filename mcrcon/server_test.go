@@ -0,0 +1,333 @@
+package mcrcon
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// testPassword is the rcon password accepted by rconHandler
+const testPassword = "secret"
+
+// handlerFunc answers a packet received by a fake server with the
+// packets to send back, which may be none
+type handlerFunc func(p *RCONPacket) []*RCONPacket
+
+// hangUp makes a fake server close the connection when a handler returns it
+var hangUp = &RCONPacket{}
+
+// readTestPacket reads a packet the way a server would, independently of
+// the client's own decoding
+func readTestPacket(r io.Reader) (*RCONPacket, error) {
+	var size int32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return &RCONPacket{
+		Size: size,
+		ID:   int32(binary.LittleEndian.Uint32(payload[0:4])),
+		Type: int32(binary.LittleEndian.Uint32(payload[4:8])),
+		Body: string(payload[8 : size-2]),
+	}, nil
+}
+
+// encodeTestPacket encodes a packet the way a server would
+func encodeTestPacket(p *RCONPacket) []byte {
+	buf := binary.LittleEndian.AppendUint32(nil, uint32(len(p.Body)+packetOverhead))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(p.ID))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(p.Type))
+	buf = append(buf, p.Body...)
+	return append(buf, 0, 0)
+}
+
+// serve answers the packets received on conn with handle until the
+// connection is closed. Responses are written from their own goroutine,
+// so a handler that sends several packets can't stall the client's next
+// write.
+func serve(conn net.Conn, handle handlerFunc) {
+	out := make(chan []byte, 64)
+
+	go func() {
+		for b := range out {
+			if b == nil {
+				conn.Close()
+				return
+			}
+			if _, err := conn.Write(b); err != nil {
+				return
+			}
+		}
+	}()
+	go func() {
+		defer close(out)
+		for {
+			packet, err := readTestPacket(conn)
+			if err != nil {
+				return
+			}
+			for _, response := range handle(packet) {
+				if response == hangUp {
+					out <- nil
+					return
+				}
+				out <- encodeTestPacket(response)
+			}
+		}
+	}()
+}
+
+// listen starts a fake server on a loopback port that answers every
+// connection with handle, returning its host and port
+func listen(t testing.TB, handle handlerFunc) (string, string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return serveListener(t, ln, handle, nil)
+}
+
+// serveListener answers every connection accepted by ln with handle until
+// the test ends, returning the listener's host and port. Accepted
+// connections are also sent to accepted unless it is nil.
+func serveListener(t testing.TB, ln net.Listener, handle handlerFunc, accepted chan<- net.Conn) (string, string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var conns []net.Conn
+	t.Cleanup(func() {
+		ln.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+			serve(conn, handle)
+			if accepted != nil {
+				accepted <- conn
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	return host, port
+}
+
+// rconHandler behaves like a Minecraft server: it accepts testPassword
+// and answers each command with reply(command)
+func rconHandler(reply func(command string) string) handlerFunc {
+	return func(p *RCONPacket) []*RCONPacket {
+		switch p.Type {
+		case rconAuthenticate:
+			id := p.ID
+			if p.Body != testPassword {
+				id = -1
+			}
+			return []*RCONPacket{{ID: id, Type: rconAuthResponse}}
+		case rconExecCommand:
+			return []*RCONPacket{{ID: p.ID, Type: rconResponseValue, Body: reply(p.Body)}}
+		}
+		return nil
+	}
+}
+
+// echo replies to a command with the command itself
+func echo(command string) string {
+	return command
+}
+
+// newTestClient returns a client connected to a fake server that answers
+// with handle
+func newTestClient(t testing.TB, config *Config, handle handlerFunc) *RCONClient {
+	t.Helper()
+
+	config.Host, config.Port = listen(t, handle)
+	client, err := NewRCONClient(config)
+	if err != nil {
+		t.Fatalf("NewRCONClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// newAuthenticatedClient returns a client that has authenticated with a
+// fake server using handle
+func newAuthenticatedClient(t testing.TB, config *Config, handle handlerFunc) *RCONClient {
+	t.Helper()
+
+	config.Password = testPassword
+	client := newTestClient(t, config, handle)
+	if err := client.Authenticate(); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	return client
+}
+
+// newPipeClient returns a client that talks over a net.Pipe to a fake
+// server answering with handle
+func newPipeClient(t testing.TB, config *Config, handle handlerFunc) *RCONClient {
+	t.Helper()
+
+	conn, server := net.Pipe()
+	serve(server, handle)
+	client := NewClientWithConn(conn, config)
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client
+}
+
+// pipeDialer is a Dialer connecting to a fake server over net.Pipe. It
+// records the addresses dialed.
+type pipeDialer struct {
+	handle handlerFunc
+
+	mu        sync.Mutex
+	addresses []string
+	servers   []net.Conn
+}
+
+func (d *pipeDialer) Dial(network, address string) (net.Conn, error) {
+	conn, server := net.Pipe()
+	serve(server, d.handle)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.addresses = append(d.addresses, address)
+	d.servers = append(d.servers, server)
+	return conn, nil
+}
+
+// dialed returns the addresses dialed so far
+func (d *pipeDialer) dialed() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.addresses)
+}
+
+// close closes the server ends of all connections
+func (d *pipeDialer) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, server := range d.servers {
+		server.Close()
+	}
+}
+
+// captureStdout returns what f prints to standard output
+func captureStdout(t testing.TB, f func()) string {
+	t.Helper()
+	return captureOutput(t, &os.Stdout, f)
+}
+
+// captureStderr returns what f prints to standard error
+func captureStderr(t testing.TB, f func()) string {
+	t.Helper()
+	return captureOutput(t, &os.Stderr, f)
+}
+
+// captureOutput returns what f writes to *file, which it replaces with a
+// pipe while f runs
+func captureOutput(t testing.TB, file **os.File, f func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := *file
+	*file = w
+	defer func() { *file = saved }()
+
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+
+	f()
+	w.Close()
+	return <-out
+}
+
+// dnsAnswer answers a DNS question with resource records or an error code
+type dnsAnswer func(q dnsmessage.Question) ([]dnsmessage.Resource, dnsmessage.RCode)
+
+// stubResolver returns a resolver whose queries are answered by answer
+// instead of name servers
+func stubResolver(answer dnsAnswer) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			conn, server := net.Pipe()
+			go serveDNS(server, answer)
+			return conn, nil
+		},
+	}
+}
+
+// serveDNS answers DNS queries on conn with answer. A net.Pipe is not a
+// PacketConn, so messages are framed with a length as over TCP.
+func serveDNS(conn net.Conn, answer dnsAnswer) {
+	defer conn.Close()
+
+	for {
+		var size uint16
+		if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+			return
+		}
+		query := make([]byte, size)
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+
+		var msg dnsmessage.Message
+		if err := msg.Unpack(query); err != nil || len(msg.Questions) == 0 {
+			return
+		}
+		msg.Response, msg.Authoritative, msg.RecursionAvailable = true, true, true
+		msg.Answers, msg.RCode = answer(msg.Questions[0])
+		msg.Additionals = nil
+
+		reply, err := msg.AppendPack(make([]byte, 2))
+		if err != nil {
+			return
+		}
+		binary.BigEndian.PutUint16(reply, uint16(len(reply)-2))
+		if _, err := conn.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+// srvRecord is an answer to q pointing at target and port
+func srvRecord(q dnsmessage.Question, target string, port uint16) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET, TTL: 60},
+		Body:   &dnsmessage.SRVResource{Port: port, Target: dnsmessage.MustNewName(target)},
+	}
+}
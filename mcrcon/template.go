@@ -0,0 +1,49 @@
+package mcrcon
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// expandVars replaces ${name} placeholders in command with the value from
+// vars or, failing that, the environment, and "$$" with a literal "$".
+// Unresolved placeholders are an error unless allowUnset is true, in
+// which case they expand to an empty string.
+func expandVars(command string, vars map[string]string, allowUnset bool) (string, error) {
+	if !strings.Contains(command, "$") {
+		return command, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(command); i++ {
+		rest := command[i:]
+		switch {
+		case strings.HasPrefix(rest, "$$"):
+			b.WriteByte('$')
+			i++
+
+		case strings.HasPrefix(rest, "${"):
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable in %q", command)
+			}
+			name := rest[2:end]
+
+			value, ok := vars[name]
+			if !ok {
+				value, ok = os.LookupEnv(name)
+			}
+			if !ok && !allowUnset {
+				return "", fmt.Errorf("variable %q is not set in %q", name, command)
+			}
+			b.WriteString(value)
+			i += end
+
+		default:
+			b.WriteByte(command[i])
+		}
+	}
+
+	return b.String(), nil
+}
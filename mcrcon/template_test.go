@@ -0,0 +1,55 @@
+package mcrcon
+
+import "testing"
+
+func TestExpandVars(t *testing.T) {
+	vars := map[string]string{"player": "Steve", "empty": ""}
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"kick ${player}", "kick Steve"},
+		{"tp ${player} ${player}", "tp Steve Steve"},
+		{"say ${empty}!", "say !"},
+		{"say $$5 for ${player}", "say $5 for Steve"},
+		{"say $${player}", "say ${player}"},
+		{"say $5 and $HOME", "say $5 and $HOME"},
+		{"list", "list"},
+	}
+
+	for _, tt := range tests {
+		got, err := expandVars(tt.command, vars, false)
+		if err != nil || got != tt.want {
+			t.Errorf("expandVars(%q) = %q, %v, want %q", tt.command, got, err, tt.want)
+		}
+	}
+}
+
+func TestExpandVarsEnvironment(t *testing.T) {
+	t.Setenv("MCRCON_TEST_WORLD", "world_nether")
+
+	got, err := expandVars("execute in ${MCRCON_TEST_WORLD} run time set day", nil, false)
+	if want := "execute in world_nether run time set day"; err != nil || got != want {
+		t.Errorf("expandVars = %q, %v, want %q", got, err, want)
+	}
+
+	// --var values take precedence over the environment
+	got, err = expandVars("${MCRCON_TEST_WORLD}", map[string]string{"MCRCON_TEST_WORLD": "world"}, false)
+	if err != nil || got != "world" {
+		t.Errorf("expandVars = %q, %v, want %q", got, err, "world")
+	}
+}
+
+func TestExpandVarsUnset(t *testing.T) {
+	for _, command := range []string{"kick ${player}", "kick ${player"} {
+		if _, err := expandVars(command, nil, false); err == nil {
+			t.Errorf("expandVars(%q) succeeded, want an error", command)
+		}
+	}
+
+	got, err := expandVars("kick ${player} now", nil, true)
+	if err != nil || got != "kick  now" {
+		t.Errorf("expandVars with allowUnset = %q, %v, want %q", got, err, "kick  now")
+	}
+}
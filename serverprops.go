@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"mcrcon-go/mcrcon"
+)
+
+// applyServerDir reads the RCON port and password from server.properties
+// in the directory given with --server-dir or in the config file. Like
+// environment variables, they are overridden by the config file and
+// command line options.
+func applyServerDir(dir string, config *mcrcon.Config) error {
+	path := filepath.Join(dir, "server.properties")
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open server properties: %w", err)
+	}
+	defer f.Close()
+
+	props, err := parseProperties(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if enabled := props["enable-rcon"]; enabled != "true" {
+		return fmt.Errorf("RCON is disabled in %s (enable-rcon=%s)", path, enabled)
+	}
+	if port := props["rcon.port"]; port != "" {
+		config.Port = port
+	}
+	if password := props["rcon.password"]; password != "" {
+		config.Password = password
+	}
+	return nil
+}
+
+// parseProperties reads a Java properties file as written by Minecraft
+// servers: "key=value" or "key: value" lines, '#' and '!' comments, and
+// backslash escapes including \uXXXX and line continuations
+func parseProperties(r io.Reader) (map[string]string, error) {
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+
+	var logical strings.Builder
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if logical.Len() == 0 && (line == "" || line[0] == '#' || line[0] == '!') {
+			continue
+		}
+
+		// An odd number of trailing backslashes continues the line
+		trailing := len(line) - len(strings.TrimRight(line, `\`))
+		if trailing%2 == 1 {
+			logical.WriteString(line[:len(line)-1])
+			continue
+		}
+		logical.WriteString(line)
+
+		key, value, err := splitProperty(logical.String())
+		if err != nil {
+			return nil, err
+		}
+		props[key] = value
+		logical.Reset()
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
+// splitProperty splits a logical properties line at the first unescaped
+// '=', ':' or whitespace and unescapes the key and value
+func splitProperty(line string) (string, string, error) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) >= 0 {
+			end = i
+			break
+		}
+	}
+
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	key, err := unescapeProperty(line[:end])
+	if err != nil {
+		return "", "", err
+	}
+	value, err := unescapeProperty(rest)
+	if err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+// unescapeProperty decodes the backslash escapes of a properties key or value
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("invalid escape %q", s[i-1:])
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape %q", s[i-1:i+5])
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
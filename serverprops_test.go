@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mcrcon-go/mcrcon"
+)
+
+func TestParseProperties(t *testing.T) {
+	text := `#Minecraft server properties
+! another comment
+enable-rcon=true
+rcon.port = 25576
+rcon.password:p\=ss\\word
+motd=A \
+    Minecraft Server §a
+level-name world
+empty=
+`
+	props, err := parseProperties(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("parseProperties: %v", err)
+	}
+
+	want := map[string]string{
+		"enable-rcon":   "true",
+		"rcon.port":     "25576",
+		"rcon.password": `p=ss\word`,
+		"motd":          "A Minecraft Server §a",
+		"level-name":    "world",
+		"empty":         "",
+	}
+	for key, value := range want {
+		if props[key] != value {
+			t.Errorf("%s = %q, want %q", key, props[key], value)
+		}
+	}
+	if len(props) != len(want) {
+		t.Errorf("parsed %d properties, want %d: %q", len(props), len(want), props)
+	}
+}
+
+func TestParsePropertiesInvalidEscape(t *testing.T) {
+	if _, err := parseProperties(strings.NewReader(`motd=\u00zz`)); err == nil {
+		t.Error("parseProperties accepted an invalid \\u escape")
+	}
+}
+
+func TestApplyServerDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(text string) {
+		if err := os.WriteFile(filepath.Join(dir, "server.properties"), []byte(text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("enable-rcon=true\nrcon.port=25580\nrcon.password=secret\n")
+	config := &mcrcon.Config{Port: mcrcon.DefaultPort}
+	if err := applyServerDir(dir, config); err != nil {
+		t.Fatalf("applyServerDir: %v", err)
+	}
+	if config.Port != "25580" || config.Password != "secret" {
+		t.Errorf("applyServerDir set port %q and password %q", config.Port, config.Password)
+	}
+
+	write("enable-rcon=false\n")
+	if err := applyServerDir(dir, &mcrcon.Config{}); err == nil {
+		t.Error("applyServerDir accepted a server with RCON disabled")
+	}
+
+	if err := applyServerDir(t.TempDir(), &mcrcon.Config{}); err == nil {
+		t.Error("applyServerDir accepted a directory without server.properties")
+	}
+}
+
+func TestServerDirFlag(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "server.properties"), []byte("enable-rcon=false\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, out := runParseFlags(t, "--server-dir", dir, "-p", "secret", "list")
+	if code != 1 || !strings.Contains(out, "RCON is disabled") {
+		t.Errorf("exit status %d, output:\n%s", code, out)
+	}
+}